@@ -0,0 +1,120 @@
+package transactstd
+
+import (
+	"context"
+	"net/url"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/sklyar/go-transact"
+	"github.com/sklyar/go-transact/internal/stack"
+)
+
+// Keys of the tags added to the statements by WithSQLCommenter.
+const (
+	TagTransactionID   = "tx_id"
+	TagTransactionName = "tx_name"
+	TagFunction        = "func"
+)
+
+// WithSQLCommenter makes the database append a comment in the sqlcommenter format
+// to every statement. The comment carries the ID and the name of the transaction,
+// the function that has executed the statement and the tags of the context.
+// Statements that already contain a comment are left as they are.
+//
+// See https://google.github.io/sqlcommenter/spec/.
+func WithSQLCommenter() Option {
+	return func(db *Database) {
+		db.comment = true
+	}
+}
+
+type tagsKey struct{}
+
+// ContextWithTags returns a context carrying the tags added to the statements
+// by WithSQLCommenter, such as the route or the request ID.
+// The tags are given as key-value pairs and are merged with the ones of the context.
+func ContextWithTags(ctx context.Context, keyvals ...string) context.Context {
+	parent, _ := ctx.Value(tagsKey{}).(map[string]string)
+
+	tags := make(map[string]string, len(parent)+len(keyvals)/2)
+	for k, v := range parent {
+		tags[k] = v
+	}
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		tags[keyvals[i]] = keyvals[i+1]
+	}
+
+	return context.WithValue(ctx, tagsKey{}, tags)
+}
+
+// commentQuery appends the sqlcommenter comment to the query.
+// The name is the name of the transaction the query is executed within, if any.
+func commentQuery(ctx context.Context, query, name string) string {
+	if strings.Contains(query, "/*") || strings.Contains(query, "--") {
+		return query
+	}
+
+	ctxTags, _ := ctx.Value(tagsKey{}).(map[string]string)
+
+	tags := make(map[string]string, len(ctxTags)+3)
+	for k, v := range ctxTags {
+		tags[k] = v
+	}
+	if id, ok := transact.TransactionID(ctx); ok {
+		tags[TagTransactionID] = id
+	}
+	if name != "" {
+		tags[TagTransactionName] = name
+	}
+	if fn := caller(); fn != "" {
+		tags[TagFunction] = fn
+	}
+	if len(tags) == 0 {
+		return query
+	}
+
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(strings.TrimRight(query, " \t\n;"))
+	b.WriteString(" /*")
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(encodeTag(k))
+		b.WriteString("='")
+		b.WriteString(encodeTag(tags[k]))
+		b.WriteByte('\'')
+	}
+	b.WriteString("*/")
+	return b.String()
+}
+
+// encodeTag URL-encodes the key or the value of a tag.
+func encodeTag(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// caller returns the name of the first function on the stack outside of this module's packages.
+func caller() string {
+	const module = "github.com/sklyar/go-transact"
+
+	frames := runtime.CallersFrames(stack.Callers(1))
+	for {
+		frame, more := frames.Next()
+		internal := strings.HasPrefix(frame.Function, module+".") || strings.HasPrefix(frame.Function, module+"/")
+		if !internal || strings.HasSuffix(frame.File, "_test.go") {
+			return frame.Function
+		}
+		if !more {
+			return ""
+		}
+	}
+}
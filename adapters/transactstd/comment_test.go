@@ -0,0 +1,53 @@
+package transactstd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sklyar/go-transact/txtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommentQuery(t *testing.T) {
+	const fn = "github.com/sklyar/go-transact/adapters/transactstd.TestCommentQuery.func1"
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		query  string
+		txName string
+		want   string
+	}{
+		{
+			name:  "outside transaction",
+			ctx:   context.Background(),
+			query: "SELECT 1",
+			want:  "SELECT 1 /*func='" + encodeTag(fn) + "'*/",
+		},
+		{
+			name:   "within transaction",
+			ctx:    ContextWithTags(txtest.WithContext(context.Background()), "route", "/orders/{id}", "request_id", "it's"),
+			query:  "SELECT 1;",
+			txName: "create order",
+			want: "SELECT 1 /*func='" + encodeTag(fn) + "',request_id='it%27s'," +
+				"route='%2Forders%2F%7Bid%7D',tx_id='1',tx_name='create%20order'*/",
+		},
+		{
+			name:  "merged tags",
+			ctx:   ContextWithTags(ContextWithTags(context.Background(), "route", "a"), "route", "b", "app", "c"),
+			query: "SELECT 1",
+			want:  "SELECT 1 /*app='c',func='" + encodeTag(fn) + "',route='b'*/",
+		},
+		{
+			name:  "existing comment",
+			ctx:   txtest.WithContext(context.Background()),
+			query: "SELECT 1 /* keep */",
+			want:  "SELECT 1 /* keep */",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, commentQuery(tt.ctx, tt.query, tt.txName))
+		})
+	}
+}
@@ -11,6 +11,9 @@ import (
 type Row struct {
 	row *stdsql.Row
 	err error
+
+	// release records that the row is closed once it is scanned or closed.
+	release func()
 }
 
 // newRow creates new Row.
@@ -23,6 +26,9 @@ func (r *Row) Scan(dest ...any) error {
 	if r.err != nil {
 		return r.err
 	}
+	if r.release != nil {
+		defer r.release()
+	}
 	return r.row.Scan(dest...)
 }
 
@@ -31,17 +37,28 @@ func (r *Row) Close() error {
 	if r.err != nil {
 		return r.err
 	}
+	if r.release != nil {
+		r.release()
+	}
 	return r.row.Err()
 }
 
 // Err implements txsql.Row interface.
 func (r *Row) Err() error {
+	if r.row == nil {
+		return r.err
+	}
 	return errors.Join(r.err, r.row.Err())
 }
 
 // Rows implements txsql.Rows interface.
 type Rows struct {
 	*stdsql.Rows
+
+	// untrack stops tracking the rows once they are closed.
+	untrack func()
+	// release records that the rows are closed.
+	release func()
 }
 
 // newRows creates new Rows.
@@ -49,6 +66,33 @@ func newRows(rows *stdsql.Rows) *Rows {
 	return &Rows{Rows: rows}
 }
 
+// Next implements txsql.Rows interface.
+func (r *Rows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+
+	// the rows are closed once they are drained.
+	if r.untrack != nil {
+		r.untrack()
+	}
+	if r.release != nil {
+		r.release()
+	}
+	return false
+}
+
+// Close implements txsql.Rows interface.
+func (r *Rows) Close() error {
+	if r.untrack != nil {
+		r.untrack()
+	}
+	if r.release != nil {
+		r.release()
+	}
+	return r.Rows.Close()
+}
+
 // Result implements txsql.Result interface.
 type Result struct {
 	stdsql.Result
@@ -62,6 +106,16 @@ func newResult(result stdsql.Result) *Result {
 // Stmt implements txsql.Stmt interface.
 type Stmt struct {
 	*stdsql.Stmt
+
+	// untrack stops tracking the statement once it is closed.
+	untrack func()
+	// guard serializes the statements executed within the transaction the statement is prepared in.
+	guard *guard
+	// query is the statement the statement has been prepared with.
+	query string
+	// resources are the resources opened within the transaction the statement is prepared in.
+	// They are nil if the resources are not tracked.
+	resources *resources
 }
 
 // newStmt creates new Stmt.
@@ -69,8 +123,21 @@ func newStmt(stmt *stdsql.Stmt) *Stmt {
 	return &Stmt{Stmt: stmt}
 }
 
+// Close implements txsql.Stmt interface.
+func (s *Stmt) Close() error {
+	if s.untrack != nil {
+		s.untrack()
+	}
+	return s.Stmt.Close()
+}
+
 // Exec implements txsql.Stmt interface.
 func (s *Stmt) Exec(args ...any) (txsql.Result, error) {
+	if err := s.guard.acquire(); err != nil {
+		return nil, err
+	}
+	defer s.guard.release()
+
 	res, err := s.Stmt.Exec(args...)
 	if err != nil {
 		return nil, err
@@ -81,16 +148,36 @@ func (s *Stmt) Exec(args ...any) (txsql.Result, error) {
 
 // Query implements txsql.Stmt interface.
 func (s *Stmt) Query(args ...any) (txsql.Rows, error) {
+	if err := s.guard.acquire(); err != nil {
+		return nil, err
+	}
+	defer s.guard.release()
+
 	rows, err := s.Stmt.Query(args...)
 	if err != nil {
 		return nil, err
 	}
 
-	return newRows(rows), nil
+	r := newRows(rows)
+	r.release = s.guard.open()
+	if s.resources != nil {
+		// closing the rows releases the connection for the next statement as well.
+		r.untrack = s.resources.track("rows", s.query, r.Close)
+	}
+	return r, nil
 }
 
 // QueryRow implements txsql.Stmt interface.
 func (s *Stmt) QueryRow(args ...any) txsql.Row {
+	if err := s.guard.acquire(); err != nil {
+		return newRow(nil, err)
+	}
+	defer s.guard.release()
+
 	row := s.Stmt.QueryRow(args...)
-	return newRow(row, nil)
+	r := &Row{row: row}
+	if row.Err() == nil {
+		r.release = s.guard.open()
+	}
+	return r
 }
@@ -12,19 +12,47 @@ import (
 type Database struct {
 	*stdsql.DB
 	txs transact.TransactionStore
+
+	tracer  txsql.Tracer
+	comment bool
+
+	trackResources      bool
+	reportOpenResources func(ctx context.Context, resources []OpenResource)
+	strictOpenResources bool
+
+	serializeStatements bool
+}
+
+// Option configures a Database.
+type Option func(db *Database)
+
+// WithTracer makes the database trace the executed statements.
+// The statements executed within a transaction are traced with the context of the transaction,
+// so they are children of the transaction traced by the manager with the same tracer.
+func WithTracer(tracer txsql.Tracer) Option {
+	return func(db *Database) {
+		db.tracer = tracer
+	}
 }
 
 // Wrap creates new wrapper for stdsql.DB.
-func Wrap(db *stdsql.DB) transact.AdapterFactoryFunc {
+func Wrap(db *stdsql.DB, opts ...Option) transact.AdapterFactoryFunc {
 	return func(transactionStore transact.TransactionStore) (txsql.DB, error) {
-		return &Database{
+		database := &Database{
 			DB:  db,
 			txs: transactionStore,
-		}, nil
+		}
+		for _, opt := range opts {
+			opt(database)
+		}
+		return database, nil
 	}
 }
 
-func (db *Database) Exec(ctx context.Context, query string, args ...any) (txsql.Result, error) {
+func (db *Database) Exec(ctx context.Context, query string, args ...any) (_ txsql.Result, err error) {
+	ctx, end := db.startQuery(ctx, query, args)
+	defer func() { end(err) }()
+
 	if tx, transacted := db.txs.Transaction(ctx); transacted {
 		res, err := tx.Exec(ctx, query, args...)
 		if err != nil {
@@ -34,7 +62,7 @@ func (db *Database) Exec(ctx context.Context, query string, args ...any) (txsql.
 		return newResult(res), nil
 	}
 
-	res, err := db.DB.ExecContext(ctx, query, args...)
+	res, err := db.DB.ExecContext(ctx, db.commentQuery(ctx, query), args...)
 	if err != nil {
 		return nil, err
 	}
@@ -42,7 +70,10 @@ func (db *Database) Exec(ctx context.Context, query string, args ...any) (txsql.
 	return newResult(res), nil
 }
 
-func (db *Database) Query(ctx context.Context, query string, args ...any) (txsql.Rows, error) {
+func (db *Database) Query(ctx context.Context, query string, args ...any) (_ txsql.Rows, err error) {
+	ctx, end := db.startQuery(ctx, query, args)
+	defer func() { end(err) }()
+
 	if tx, transacted := db.txs.Transaction(ctx); transacted {
 		rows, err := tx.Query(ctx, query, args...)
 		if err != nil {
@@ -51,7 +82,7 @@ func (db *Database) Query(ctx context.Context, query string, args ...any) (txsql
 		return rows, nil
 	}
 
-	rows, err := db.DB.QueryContext(ctx, query, args...)
+	rows, err := db.DB.QueryContext(ctx, db.commentQuery(ctx, query), args...)
 	if err != nil {
 		return nil, err
 	}
@@ -60,25 +91,35 @@ func (db *Database) Query(ctx context.Context, query string, args ...any) (txsql
 }
 
 func (db *Database) QueryRow(ctx context.Context, query string, args ...any) txsql.Row {
+	ctx, end := db.startQuery(ctx, query, args)
+
+	var row txsql.Row
 	if tx, transacted := db.txs.Transaction(ctx); transacted {
-		return tx.QueryRow(ctx, query, args...)
+		row = tx.QueryRow(ctx, query, args...)
+	} else {
+		row = newRow(db.DB.QueryRowContext(ctx, db.commentQuery(ctx, query), args...), nil)
 	}
 
-	row := db.DB.QueryRowContext(ctx, query, args...)
-	return newRow(row, nil)
+	end(row.Err())
+	return row
 }
 
-func (db *Database) Prepare(ctx context.Context, query string) (txsql.Stmt, error) {
+func (db *Database) Prepare(ctx context.Context, query string) (_ txsql.Stmt, err error) {
+	ctx, end := db.startQuery(ctx, query, nil)
+	defer func() { end(err) }()
+
 	if tx, transacted := db.txs.Transaction(ctx); transacted {
 		return tx.Prepare(ctx, query)
 	}
 
-	stmt, err := db.DB.PrepareContext(ctx, query)
+	stmt, err := db.DB.PrepareContext(ctx, db.commentQuery(ctx, query))
 	if err != nil {
 		return nil, err
 	}
 
-	return newStmt(stmt), nil
+	s := newStmt(stmt)
+	s.query = query
+	return s, nil
 }
 
 func (db *Database) Begin(ctx context.Context, opts *txsql.TxOptions) (txsql.Tx, error) {
@@ -95,7 +136,44 @@ func (db *Database) Begin(ctx context.Context, opts *txsql.TxOptions) (txsql.Tx,
 		return nil, err
 	}
 
-	return &tx{Tx: sqlTx}, nil
+	t := &tx{Tx: sqlTx, ctx: ctx, comment: db.comment}
+	if opts != nil {
+		t.name = opts.Name
+	}
+	if db.trackResources {
+		t.resources = new(resources)
+		t.reportOpenResources = db.reportOpenResources
+		t.strictOpenResources = db.strictOpenResources
+	}
+	if db.serializeStatements {
+		t.guard = new(guard)
+	}
+	return t, nil
+}
+
+// commentQuery appends the sqlcommenter comment to a statement executed outside a transaction
+// if the database is created with WithSQLCommenter.
+func (db *Database) commentQuery(ctx context.Context, query string) string {
+	if !db.comment {
+		return query
+	}
+	return commentQuery(ctx, query, "")
+}
+
+// startQuery starts tracing the statement if the database has a tracer.
+// The returned function ends the trace with the error of the statement.
+func (db *Database) startQuery(ctx context.Context, query string, args []any) (context.Context, func(err error)) {
+	if db.tracer == nil {
+		return ctx, func(error) {}
+	}
+
+	info := txsql.QueryInfo{Query: query, Args: args}
+	info.TxID, _ = transact.TransactionID(ctx)
+
+	ctx = db.tracer.StartQuery(ctx, info)
+	return ctx, func(err error) {
+		db.tracer.EndQuery(ctx, err)
+	}
 }
 
 func (db *Database) Ping(ctx context.Context) error {
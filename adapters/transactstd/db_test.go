@@ -8,15 +8,19 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"sync"
 	"testing"
+	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/sklyar/go-transact"
 	"github.com/sklyar/go-transact/txsql"
+	"github.com/sklyar/go-transact/txtest"
 	"github.com/stretchr/testify/require"
 )
 
 var (
+	sqlDB     *sql.DB
 	db        txsql.DB
 	txManager *transact.Manager
 )
@@ -43,7 +47,7 @@ func TestMain(m *testing.M) {
 	}
 	defer container.Close(ctx)
 
-	sqlDB, err := sql.Open("pgx", container.ConnectionStr)
+	sqlDB, err = sql.Open("pgx", container.ConnectionStr)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -331,6 +335,338 @@ func TestDatabase_FailedPrepareInTx(t *testing.T) {
 	assertRowsCount(t, ctx, table, 0)
 }
 
+func TestDatabase_NestedInTx(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	const table = "test_nested_in_tx"
+	entity := newTestEntity(1, "test")
+	entity2 := newTestEntity(2, "test2")
+
+	setupTable(ctx, t, table)
+
+	someErr := fmt.Errorf("some error")
+	err := txManager.BeginFunc(ctx, func(tx context.Context) error {
+		insertTestData(tx, t, table, entity)
+
+		// the savepoints of the failed scopes must not pile up.
+		for i := 0; i < 100; i++ {
+			err := txManager.BeginFunc(tx, func(tx context.Context) error {
+				insertTestData(tx, t, table, entity2)
+				return someErr
+			}, txsql.WithNested())
+			require.ErrorIs(t, err, someErr)
+		}
+
+		// only the nested inserts must be rolled back.
+		assertRowsCount(t, tx, table, 1)
+
+		// the savepoint has been released after the rollback to it.
+		_, err := db.Exec(tx, "SAVEPOINT probe")
+		require.NoError(t, err)
+		_, err = db.Exec(tx, "RELEASE SAVEPOINT sp_1")
+		require.Error(t, err)
+		_, err = db.Exec(tx, "ROLLBACK TO SAVEPOINT probe")
+		require.NoError(t, err)
+		return nil
+	})
+	require.NoError(t, err)
+
+	assertRowsCount(t, ctx, table, 1)
+}
+
+func TestDatabase_RequiresNewInTx(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	const table = "test_requires_new_in_tx"
+	entity := newTestEntity(1, "test")
+	audit := newTestEntity(2, "audit")
+
+	setupTable(ctx, t, table)
+
+	someErr := fmt.Errorf("some error")
+	err := txManager.BeginFunc(ctx, func(tx context.Context) error {
+		insertTestData(tx, t, table, entity)
+
+		err := txManager.BeginFunc(tx, func(tx context.Context) error {
+			insertTestData(tx, t, table, audit)
+			return nil
+		}, txsql.WithPropagation(txsql.PropagationRequiresNew))
+		require.NoError(t, err)
+
+		return someErr
+	})
+	require.ErrorContains(t, err, someErr.Error())
+
+	// the record of the independent transaction must survive the outer rollback.
+	row := db.QueryRow(ctx, "SELECT * FROM test_requires_new_in_tx")
+	assertRowValues(t, row, audit)
+}
+
+func TestDatabase_TracerInTx(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	const table = "test_tracer_in_tx"
+
+	setupTable(ctx, t, table)
+
+	tracer := txtest.NewTracer()
+	tracedManager, tracedDB, err := transact.NewManager(Wrap(sqlDB, WithTracer(tracer)), transact.WithTracer(tracer))
+	require.NoError(t, err)
+
+	query := fmt.Sprintf("INSERT INTO %s (id, name) VALUES ($1, $2)", table)
+	err = tracedManager.BeginFunc(ctx, func(ctx context.Context) error {
+		_, err := tracedDB.Exec(ctx, query, 1, "test")
+		return err
+	})
+	require.NoError(t, err)
+
+	spans := tracer.Spans()
+	require.Len(t, spans, 2)
+
+	txSpan, querySpan := spans[0], spans[1]
+	require.Equal(t, txtest.SpanTransaction, txSpan.Name)
+	require.True(t, txSpan.Ended)
+	require.True(t, txSpan.Committed)
+
+	require.Equal(t, txtest.SpanQuery, querySpan.Name)
+	require.Equal(t, query, querySpan.Query)
+	require.Equal(t, txSpan.TxID, querySpan.TxID)
+	require.Same(t, txSpan, querySpan.Parent)
+	require.True(t, querySpan.Ended)
+	require.NoError(t, querySpan.Err)
+}
+
+func TestDatabase_OpenRowsInTx(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	const table = "test_open_rows_in_tx"
+	entity := newTestEntity(1, "test")
+
+	setupTable(ctx, t, table)
+	insertTestData(ctx, t, table, entity)
+
+	query := fmt.Sprintf("SELECT id, name FROM %s", table)
+
+	t.Run("hook", func(t *testing.T) {
+		var reported []OpenResource
+		hookManager, hookDB, err := transact.NewManager(Wrap(sqlDB, WithOpenResourcesHook(
+			func(_ context.Context, resources []OpenResource) {
+				reported = append(reported, resources...)
+			},
+		)))
+		require.NoError(t, err)
+
+		err = hookManager.BeginFunc(ctx, func(ctx context.Context) error {
+			_, err := hookDB.Query(ctx, query)
+			return err
+		})
+		require.NoError(t, err)
+
+		require.Len(t, reported, 1)
+		require.Equal(t, "rows", reported[0].Kind)
+		require.Equal(t, query, reported[0].Query)
+		require.Contains(t, reported[0].Stack, "TestDatabase_OpenRowsInTx")
+	})
+
+	t.Run("hook using the transaction", func(t *testing.T) {
+		var hookErr error
+		var hookDB txsql.DB
+		hookManager, hookDB, err := transact.NewManager(Wrap(sqlDB, WithSerializedStatements(), WithOpenResourcesHook(
+			func(ctx context.Context, _ []OpenResource) {
+				// the connection is released by the time the hook is called.
+				_, hookErr = hookDB.Exec(ctx, "SELECT 1")
+			},
+		)))
+		require.NoError(t, err)
+
+		err = hookManager.BeginFunc(ctx, func(ctx context.Context) error {
+			_, err := hookDB.Query(ctx, query)
+			return err
+		})
+		require.NoError(t, err)
+		require.NoError(t, hookErr)
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		strictManager, strictDB, err := transact.NewManager(Wrap(sqlDB, WithStrictOpenResources()))
+		require.NoError(t, err)
+
+		err = strictManager.BeginFunc(ctx, func(ctx context.Context) error {
+			if _, err := strictDB.Exec(ctx, fmt.Sprintf("DELETE FROM %s", table)); err != nil {
+				return err
+			}
+
+			_, err := strictDB.Query(ctx, query)
+			return err
+		})
+
+		var openErr *OpenResourcesError
+		require.ErrorAs(t, err, &openErr)
+		require.Len(t, openErr.Resources, 1)
+		require.Equal(t, query, openErr.Resources[0].Query)
+
+		// the transaction has been rolled back.
+		assertRowsCount(t, ctx, table, 1)
+	})
+
+	t.Run("statement rows", func(t *testing.T) {
+		strictManager, strictDB, err := transact.NewManager(Wrap(sqlDB, WithStrictOpenResources()))
+		require.NoError(t, err)
+
+		err = strictManager.BeginFunc(ctx, func(ctx context.Context) error {
+			stmt, err := strictDB.Prepare(ctx, query)
+			if err != nil {
+				return err
+			}
+			defer stmt.Close()
+
+			_, err = stmt.Query()
+			return err
+		})
+
+		var openErr *OpenResourcesError
+		require.ErrorAs(t, err, &openErr)
+		require.Len(t, openErr.Resources, 1)
+		require.Equal(t, "rows", openErr.Resources[0].Kind)
+		require.Equal(t, query, openErr.Resources[0].Query)
+	})
+
+	t.Run("closed rows", func(t *testing.T) {
+		strictManager, strictDB, err := transact.NewManager(Wrap(sqlDB, WithStrictOpenResources()))
+		require.NoError(t, err)
+
+		err = strictManager.BeginFunc(ctx, func(ctx context.Context) error {
+			rows, err := strictDB.Query(ctx, query)
+			if err != nil {
+				return err
+			}
+			assertRowsValues(t, rows, entity)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+}
+
+func TestDatabase_SerializedStatementsInTx(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	const table = "test_serialized_statements_in_tx"
+	entity := newTestEntity(1, "test")
+
+	setupTable(ctx, t, table)
+	insertTestData(ctx, t, table, entity)
+
+	serialManager, serialDB, err := transact.NewManager(Wrap(sqlDB, WithSerializedStatements()))
+	require.NoError(t, err)
+
+	query := fmt.Sprintf("SELECT id, name FROM %s", table)
+
+	err = serialManager.BeginFunc(ctx, func(ctx context.Context) error {
+		var wg sync.WaitGroup
+		errs := make([]error, 8)
+		for i := range errs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+
+				var id int
+				errs[i] = serialDB.QueryRow(ctx, fmt.Sprintf("SELECT id FROM %s", table)).Scan(&id)
+			}(i)
+		}
+		wg.Wait()
+		for _, err := range errs {
+			require.NoError(t, err)
+		}
+
+		rows, err := serialDB.Query(ctx, query)
+		require.NoError(t, err)
+
+		// the connection is busy until the rows are closed.
+		_, err = serialDB.Exec(ctx, fmt.Sprintf("DELETE FROM %s", table))
+		require.ErrorIs(t, err, ErrTransactionBusy)
+
+		assertRowsValues(t, rows, entity)
+
+		_, err = serialDB.Exec(ctx, fmt.Sprintf("DELETE FROM %s", table))
+		return err
+	})
+	require.NoError(t, err)
+
+	assertRowsCount(t, ctx, table, 0)
+}
+
+func TestDatabase_RollbackOnCancel(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	const table = "test_rollback_on_cancel"
+	setupTable(ctx, t, table)
+
+	cancelCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	err := txManager.BeginFunc(cancelCtx, func(tx context.Context) error {
+		query := fmt.Sprintf("INSERT INTO %s (id, name) VALUES ($1, $2)", table)
+		if _, err := db.Exec(tx, query, 1, "test"); err != nil {
+			return err
+		}
+
+		cancel()
+		return nil
+	})
+	require.ErrorIs(t, err, context.Canceled)
+
+	// the transaction has been rolled back by database/sql already, which is not a rollback failure.
+	var txErr *transact.TxError
+	require.ErrorAs(t, err, &txErr)
+	require.NoError(t, txErr.RollbackErr)
+
+	assertRowsCount(t, ctx, table, 0)
+}
+
+func TestDatabase_ExecAfterWatchdogRollback(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	const table = "test_exec_after_watchdog_rollback"
+	setupTable(ctx, t, table)
+
+	var reported sync.WaitGroup
+	reported.Add(1)
+	manager, db, err := transact.NewManager(Wrap(sqlDB), transact.WithWatchdog(transact.WatchdogOptions{
+		Threshold:     10 * time.Millisecond,
+		OnLongRunning: func(transact.LongRunningTransaction) { reported.Done() },
+		RollBack:      true,
+	}))
+	require.NoError(t, err)
+	defer manager.Close()
+
+	err = manager.BeginFunc(ctx, func(tx context.Context) error {
+		reported.Wait()
+
+		// the transaction has been rolled back, so the statement must not run in auto-commit mode.
+		query := fmt.Sprintf("INSERT INTO %s (id, name) VALUES ($1, $2)", table)
+		_, err := db.Exec(tx, query, 1, "test")
+		require.ErrorIs(t, err, sql.ErrTxDone)
+		return nil
+	})
+	require.ErrorIs(t, err, transact.ErrLongRunningTransaction)
+
+	assertRowsCount(t, ctx, table, 0)
+}
+
 func TestDatabase_Ping(t *testing.T) {
 	t.Parallel()
 
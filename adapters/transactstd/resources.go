@@ -0,0 +1,133 @@
+package transactstd
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/sklyar/go-transact/internal/stack"
+)
+
+// OpenResource describes rows or a statement that were still open when their transaction completed.
+type OpenResource struct {
+	// Kind is either "rows" or "stmt".
+	Kind string
+
+	// Query is the statement the resource has been opened with.
+	Query string
+
+	// Stack is the stack trace of the call that has opened the resource.
+	Stack string
+}
+
+// OpenResourcesError is returned by Commit when rows or statements opened within the transaction
+// are still open and the database is created with WithStrictOpenResources.
+type OpenResourcesError struct {
+	// Resources are the resources that were still open.
+	Resources []OpenResource
+}
+
+// Error implements error interface.
+func (e *OpenResourcesError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "transaction has %d open resource(s)", len(e.Resources))
+	for _, r := range e.Resources {
+		fmt.Fprintf(&b, "\n%s %q opened at:\n%s", r.Kind, r.Query, r.Stack)
+	}
+	return b.String()
+}
+
+// WithOpenResourcesHook makes the database track the rows and statements opened within transactions.
+// The ones still open when the transaction is committed or rolled back are closed
+// and reported to the hook before the transaction completes.
+func WithOpenResourcesHook(hook func(ctx context.Context, resources []OpenResource)) Option {
+	return func(db *Database) {
+		db.trackResources = true
+		db.reportOpenResources = hook
+	}
+}
+
+// WithStrictOpenResources makes the database track the rows and statements opened within transactions.
+// If any of them is still open when the transaction is committed, they are closed, the transaction
+// is rolled back and Commit returns an *OpenResourcesError listing where each of them has been opened.
+// Open resources do not fail a rollback.
+func WithStrictOpenResources() Option {
+	return func(db *Database) {
+		db.trackResources = true
+		db.strictOpenResources = true
+	}
+}
+
+// resource is a tracked resource opened within a transaction.
+type resource struct {
+	kind  string
+	query string
+	pcs   []uintptr
+	close func() error
+}
+
+// resources tracks the resources opened within a transaction.
+type resources struct {
+	mu   sync.Mutex
+	open []*resource
+}
+
+// track starts tracking the resource. The returned function stops tracking it.
+func (rs *resources) track(kind, query string, closeFn func() error) func() {
+	r := &resource{kind: kind, query: query, pcs: stack.Callers(1), close: closeFn}
+
+	rs.mu.Lock()
+	rs.open = append(rs.open, r)
+	rs.mu.Unlock()
+
+	return func() {
+		rs.mu.Lock()
+		defer rs.mu.Unlock()
+
+		for i, open := range rs.open {
+			if open == r {
+				rs.open = append(rs.open[:i], rs.open[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// takeAll stops tracking the resources that are still open and returns them in the order they have been opened.
+func (rs *resources) takeAll() []*resource {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	open := rs.open
+	rs.open = nil
+	return open
+}
+
+// closeResources closes the resources and returns their descriptions.
+func closeResources(open []*resource) []OpenResource {
+	closed := make([]OpenResource, 0, len(open))
+	for _, r := range open {
+		_ = r.close()
+		closed = append(closed, OpenResource{
+			Kind:  r.kind,
+			Query: r.query,
+			Stack: formatStack(r.pcs),
+		})
+	}
+	return closed
+}
+
+// formatStack formats the stack skipping the frames of the adapter and the manager.
+func formatStack(pcs []uintptr) string {
+	const (
+		adapterPkg = "github.com/sklyar/go-transact/adapters/transactstd."
+		managerPkg = "github.com/sklyar/go-transact.(*Transaction)"
+	)
+
+	return stack.Format(pcs, func(frame runtime.Frame) bool {
+		return strings.HasPrefix(frame.Function, adapterPkg) && !strings.HasSuffix(frame.File, "_test.go") ||
+			strings.HasPrefix(frame.Function, managerPkg)
+	})
+}
@@ -0,0 +1,81 @@
+package transactstd
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrTransactionBusy is returned when a statement is executed within a transaction
+// whose connection is still busy with the rows of a previous statement.
+// It is returned only if the database is created with WithSerializedStatements.
+var ErrTransactionBusy = errors.New("transaction is busy: rows of a previous statement are still open")
+
+// WithSerializedStatements makes the database serialize the statements executed within a transaction.
+// A transaction runs on a single connection, which cannot execute statements concurrently,
+// so goroutines sharing the context of a transaction wait for each other.
+// A statement executed while rows of the transaction are still open fails with ErrTransactionBusy
+// instead of corrupting the connection. The rows are open until they are closed or drained,
+// and a row is open until it is scanned or closed.
+func WithSerializedStatements() Option {
+	return func(db *Database) {
+		db.serializeStatements = true
+	}
+}
+
+// guard serializes the statements executed on the connection of a transaction.
+// A nil guard does nothing.
+type guard struct {
+	mu sync.Mutex
+	// openRows is the number of rows opened on the connection that are not closed yet.
+	openRows int
+}
+
+// acquire waits for the statement in progress, if any, to finish.
+// It returns ErrTransactionBusy if the connection is busy with open rows.
+// If it succeeds, the caller must call release once the statement is executed.
+func (g *guard) acquire() error {
+	if g == nil {
+		return nil
+	}
+
+	g.mu.Lock()
+	if g.openRows > 0 {
+		g.mu.Unlock()
+		return ErrTransactionBusy
+	}
+	return nil
+}
+
+// lock waits for the statement in progress, if any, to finish, regardless of the open rows.
+// The caller must call release once it is done.
+func (g *guard) lock() {
+	if g != nil {
+		g.mu.Lock()
+	}
+}
+
+// release allows the next statement to be executed.
+func (g *guard) release() {
+	if g != nil {
+		g.mu.Unlock()
+	}
+}
+
+// open records that rows have been opened by the statement in progress.
+// The returned function records that they have been closed, it may be called more than once.
+// The caller must hold the guard.
+func (g *guard) open() func() {
+	if g == nil {
+		return nil
+	}
+
+	g.openRows++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			g.mu.Lock()
+			g.openRows--
+			g.mu.Unlock()
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package transactstd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGuard(t *testing.T) {
+	t.Parallel()
+
+	g := new(guard)
+
+	require.NoError(t, g.acquire())
+	closeRows := g.open()
+	g.release()
+
+	// the connection is busy until the rows are closed.
+	assert.ErrorIs(t, g.acquire(), ErrTransactionBusy)
+
+	closeRows()
+	closeRows()
+
+	require.NoError(t, g.acquire())
+	g.release()
+	assert.Zero(t, g.openRows)
+}
+
+func TestGuard_Nil(t *testing.T) {
+	t.Parallel()
+
+	var g *guard
+
+	require.NoError(t, g.acquire())
+	assert.Nil(t, g.open())
+	g.lock()
+	g.release()
+}
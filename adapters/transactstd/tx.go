@@ -3,49 +3,200 @@ package transactstd
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 
 	"github.com/sklyar/go-transact/txsql"
 )
 
 type tx struct {
 	*sql.Tx
+
+	// ctx is the context the transaction has been begun with.
+	ctx context.Context
+
+	// name is the name of the transaction.
+	name string
+	// comment is true if the statements are tagged with sqlcommenter comments.
+	comment bool
+
+	// resources are the resources opened within the transaction.
+	// They are nil if the resources are not tracked.
+	resources           *resources
+	reportOpenResources func(ctx context.Context, resources []OpenResource)
+	strictOpenResources bool
+
+	// guard serializes the statements executed within the transaction.
+	// It is nil if the statements are not serialized.
+	guard *guard
 }
 
 func (t *tx) Exec(ctx context.Context, query string, args ...any) (txsql.Result, error) {
-	return t.Tx.ExecContext(ctx, query, args...)
+	if err := t.guard.acquire(); err != nil {
+		return nil, err
+	}
+	defer t.guard.release()
+
+	return t.Tx.ExecContext(ctx, t.commentQuery(ctx, query), args...)
 }
 
 func (t *tx) Query(ctx context.Context, query string, args ...any) (txsql.Rows, error) {
-	rows, err := t.Tx.QueryContext(ctx, query, args...)
+	if err := t.guard.acquire(); err != nil {
+		return nil, err
+	}
+	defer t.guard.release()
+
+	rows, err := t.Tx.QueryContext(ctx, t.commentQuery(ctx, query), args...)
 	if err != nil {
 		return nil, err
 	}
 
-	return newRows(rows), nil
+	r := newRows(rows)
+	r.release = t.guard.open()
+	if t.resources != nil {
+		// closing the rows releases the connection for the next statement as well.
+		r.untrack = t.resources.track("rows", query, r.Close)
+	}
+	return r, nil
 }
 
 func (t *tx) QueryRow(ctx context.Context, query string, args ...any) txsql.Row {
-	row := t.Tx.QueryRowContext(ctx, query, args...)
-	return newRow(row, nil)
+	if err := t.guard.acquire(); err != nil {
+		return newRow(nil, err)
+	}
+	defer t.guard.release()
+
+	row := t.Tx.QueryRowContext(ctx, t.commentQuery(ctx, query), args...)
+	r := &Row{row: row}
+	if row.Err() == nil {
+		r.release = t.guard.open()
+	}
+	return r
 }
 
 func (t *tx) Prepare(ctx context.Context, query string) (txsql.Stmt, error) {
-	stmt, err := t.Tx.PrepareContext(ctx, query)
+	if err := t.guard.acquire(); err != nil {
+		return nil, err
+	}
+	defer t.guard.release()
+
+	stmt, err := t.Tx.PrepareContext(ctx, t.commentQuery(ctx, query))
 	if err != nil {
 		return nil, err
 	}
 
-	return newStmt(stmt), nil
+	s := newStmt(stmt)
+	s.guard = t.guard
+	s.query = query
+	if t.resources != nil {
+		s.resources = t.resources
+		s.untrack = t.resources.track("stmt", query, stmt.Close)
+	}
+	return s, nil
 }
 
-func (t *tx) Commit(_ context.Context) error {
+func (t *tx) Commit(ctx context.Context) error {
+	open := t.closeOpenResources(ctx)
+
+	t.guard.lock()
+	defer t.guard.release()
+
+	if len(open) > 0 && t.strictOpenResources {
+		err := &OpenResourcesError{Resources: open}
+		if rerr := t.Tx.Rollback(); rerr != nil {
+			return errors.Join(err, fmt.Errorf("failed to rollback transaction: %w", rerr))
+		}
+		return err
+	}
+
 	return t.Tx.Commit()
 }
 
-func (t *tx) Rollback(_ context.Context) error {
-	return t.Tx.Rollback()
+func (t *tx) Rollback(ctx context.Context) error {
+	t.closeOpenResources(ctx)
+
+	t.guard.lock()
+	defer t.guard.release()
+
+	err := t.Tx.Rollback()
+	if errors.Is(err, sql.ErrTxDone) && t.ctx.Err() != nil {
+		// database/sql has already rolled the transaction back
+		// once the context it has been begun with was done.
+		return nil
+	}
+	return err
 }
 
 func (t *tx) Stmt(stmt txsql.Stmt) txsql.Stmt {
-	return newStmt(t.Tx.Stmt(stmt.(*Stmt).Stmt))
+	t.guard.lock()
+	defer t.guard.release()
+
+	prepared := stmt.(*Stmt)
+	s := newStmt(t.Tx.Stmt(prepared.Stmt))
+	s.guard = t.guard
+	s.query = prepared.query
+	if t.resources != nil {
+		s.resources = t.resources
+		s.untrack = t.resources.track("stmt", s.query, s.Stmt.Close)
+	}
+	return s
+}
+
+// commentQuery appends the sqlcommenter comment to a statement executed within the transaction
+// if the database is created with WithSQLCommenter.
+func (t *tx) commentQuery(ctx context.Context, query string) string {
+	if !t.comment {
+		return query
+	}
+	return commentQuery(ctx, query, t.name)
+}
+
+// closeOpenResources closes the resources that are still open within the transaction
+// and reports them to the hook, if any. It returns the closed resources.
+// The resources are collected while holding the guard, but they are closed and reported without it,
+// so the hook may execute statements within the transaction.
+func (t *tx) closeOpenResources(ctx context.Context) []OpenResource {
+	if t.resources == nil {
+		return nil
+	}
+
+	t.guard.lock()
+	taken := t.resources.takeAll()
+	t.guard.release()
+
+	open := closeResources(taken)
+	if len(open) > 0 && t.reportOpenResources != nil {
+		t.reportOpenResources(ctx, open)
+	}
+	return open
+}
+
+func (t *tx) Savepoint(ctx context.Context, name string) error {
+	if err := t.guard.acquire(); err != nil {
+		return err
+	}
+	defer t.guard.release()
+
+	_, err := t.Tx.ExecContext(ctx, "SAVEPOINT "+name)
+	return err
+}
+
+func (t *tx) RollbackToSavepoint(ctx context.Context, name string) error {
+	if err := t.guard.acquire(); err != nil {
+		return err
+	}
+	defer t.guard.release()
+
+	_, err := t.Tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+	return err
+}
+
+func (t *tx) ReleaseSavepoint(ctx context.Context, name string) error {
+	if err := t.guard.acquire(); err != nil {
+		return err
+	}
+	defer t.guard.release()
+
+	_, err := t.Tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	return err
 }
@@ -0,0 +1,85 @@
+package transact
+
+import (
+	"fmt"
+	"time"
+)
+
+// PanicError is returned by BeginFunc with txsql.WithPanicAsError
+// if the transaction function panics.
+type PanicError struct {
+	// Value is the value the transaction function panicked with.
+	Value any
+	// Stack is the stack trace of the panic.
+	Stack []byte
+}
+
+// Error implements error interface.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("transaction function panicked: %v", e.Value)
+}
+
+// Unwrap returns the value the transaction function panicked with if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// TxError is returned by BeginFunc and Do if the transaction fails.
+// The operation tells where it has failed: OpBegin and OpStore before the transaction function
+// is executed, OpExec if the function has returned an error or panicked, and OpCommit
+// if the function has succeeded but the transaction has failed to commit.
+type TxError struct {
+	// Op is the operation that has failed.
+	Op Operation
+
+	// ID is the ID of the transaction.
+	// It is empty if the scope has run without a transaction.
+	ID string
+
+	// Depth is the nesting level of the transaction scope.
+	// The root transaction has depth 0.
+	Depth int
+
+	// Duration is the time the scope has been running for until it failed.
+	Duration time.Duration
+
+	// Err is the error of the operation.
+	Err error
+
+	// RollbackErr is the error of the rollback performed after the operation has failed, if any.
+	RollbackErr error
+}
+
+// Error implements error interface.
+func (e *TxError) Error() string {
+	var msg string
+	switch e.Op {
+	case OpBegin:
+		msg = "failed to begin transaction: "
+	case OpStore:
+		msg = "failed to add transaction: "
+	case OpExec:
+		msg = "failed to execute transaction function: "
+	case OpCommit:
+		msg = "failed to commit transaction: "
+	case OpRollback:
+		msg = "failed to rollback transaction: "
+	default:
+		msg = fmt.Sprintf("transaction %s failed: ", e.Op)
+	}
+	msg += e.Err.Error()
+
+	if e.RollbackErr != nil {
+		msg += "\nfailed to rollback transaction: " + e.RollbackErr.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the error of the operation and the error of the rollback, if any.
+func (e *TxError) Unwrap() []error {
+	if e.RollbackErr == nil {
+		return []error{e.Err}
+	}
+	return []error{e.Err, e.RollbackErr}
+}
@@ -75,39 +75,31 @@ func main() {
 
 // Create creates a new order and adds products to it
 func (s *OrderService) Create(ctx context.Context, customerID int, products []int) (int, error) {
-	ctx, tx, err := s.txManager.Begin(ctx)
-	if err != nil {
-		return 0, fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback(ctx)
-
-	orderID, err := s.orderRepo.Create(ctx, customerID)
-	if err != nil {
-		return 0, fmt.Errorf("failed to create order: %w", err)
-	}
-	for _, productID := range products {
-		quantity, err := s.inventoryRepo.GetProductQuantity(ctx, productID)
+	return transact.Do(ctx, s.txManager, func(ctx context.Context) (int, error) {
+		orderID, err := s.orderRepo.Create(ctx, customerID)
 		if err != nil {
-			return 0, fmt.Errorf("failed to get product quantity: %w", err)
-		}
-		if quantity < 1 {
-			return 0, fmt.Errorf("not enough quantity for product %d", productID)
+			return 0, fmt.Errorf("failed to create order: %w", err)
 		}
-
-		if err := s.orderRepo.AddProduct(ctx, orderID, productID); err != nil {
-			return 0, fmt.Errorf("failed to add product to order: %w", err)
+		for _, productID := range products {
+			quantity, err := s.inventoryRepo.GetProductQuantity(ctx, productID)
+			if err != nil {
+				return 0, fmt.Errorf("failed to get product quantity: %w", err)
+			}
+			if quantity < 1 {
+				return 0, fmt.Errorf("not enough quantity for product %d", productID)
+			}
+
+			if err := s.orderRepo.AddProduct(ctx, orderID, productID); err != nil {
+				return 0, fmt.Errorf("failed to add product to order: %w", err)
+			}
+
+			if err := s.inventoryRepo.DecrementProductQuantity(ctx, productID); err != nil {
+				return 0, fmt.Errorf("failed to update inventory: %w", err)
+			}
 		}
 
-		if err := s.inventoryRepo.DecrementProductQuantity(ctx, productID); err != nil {
-			return 0, fmt.Errorf("failed to update inventory: %w", err)
-		}
-	}
-
-	if _, err := tx.Commit(ctx); err != nil {
-		return 0, fmt.Errorf("failed to commit: %w", err)
-	}
-
-	return orderID, nil
+		return orderID, nil
+	})
 }
 
 type orderRepository struct {
@@ -45,15 +45,15 @@ func TestApp_CreateOrder(t *testing.T) {
 				ctx := txtest.WithContext(ctx)
 
 				m.db.On("Begin", ctx, (*txsql.TxOptions)(nil)).Return(m.tx, nil)
-				m.orderRepo.On("Create", ctx, 1).Return(1, nil)
+				m.orderRepo.On("Create", txtest.MatchContext(ctx), 1).Return(1, nil)
 
 				for _, productID := range in.products {
-					m.inventoryRepo.On("GetProductQuantity", ctx, productID).Return(3, nil)
-					m.orderRepo.On("AddProduct", ctx, 1, productID).Return(nil)
-					m.inventoryRepo.On("DecrementProductQuantity", ctx, productID).Return(nil)
+					m.inventoryRepo.On("GetProductQuantity", txtest.MatchContext(ctx), productID).Return(3, nil)
+					m.orderRepo.On("AddProduct", txtest.MatchContext(ctx), 1, productID).Return(nil)
+					m.inventoryRepo.On("DecrementProductQuantity", txtest.MatchContext(ctx), productID).Return(nil)
 				}
 
-				m.tx.On("Commit", ctx).Return(nil)
+				m.tx.On("Commit", txtest.MatchContext(ctx)).Return(nil)
 			},
 		},
 		{
@@ -63,14 +63,14 @@ func TestApp_CreateOrder(t *testing.T) {
 				ctx := txtest.WithContext(ctx)
 
 				m.db.On("Begin", ctx, (*txsql.TxOptions)(nil)).Return(m.tx, nil)
-				m.orderRepo.On("Create", ctx, 1).Return(1, nil)
+				m.orderRepo.On("Create", txtest.MatchContext(ctx), 1).Return(1, nil)
 
 				productID := in.products[0]
-				m.inventoryRepo.On("GetProductQuantity", ctx, productID).Return(3, nil)
-				m.orderRepo.On("AddProduct", ctx, 1, productID).Return(nil)
-				m.inventoryRepo.On("DecrementProductQuantity", ctx, productID).Return(errors.New("some error"))
+				m.inventoryRepo.On("GetProductQuantity", txtest.MatchContext(ctx), productID).Return(3, nil)
+				m.orderRepo.On("AddProduct", txtest.MatchContext(ctx), 1, productID).Return(nil)
+				m.inventoryRepo.On("DecrementProductQuantity", txtest.MatchContext(ctx), productID).Return(errors.New("some error"))
 
-				m.tx.On("Rollback", ctx).Return(nil)
+				m.tx.On("Rollback", txtest.MatchContext(ctx)).Return(nil)
 			},
 			wantErr: "some error",
 		},
@@ -0,0 +1,158 @@
+package transact
+
+import (
+	"context"
+	"sync"
+)
+
+// hooks holds the callbacks registered on a transaction.
+type hooks struct {
+	mu sync.Mutex
+
+	beforeCommit  []func(ctx context.Context) error
+	afterCommit   []func(ctx context.Context)
+	afterRollback []func(ctx context.Context)
+
+	// savepoints are the numbers of hooks registered before each savepoint has been created.
+	// The hooks registered after a savepoint are dropped once the transaction is rolled back to it.
+	savepoints map[string]hooksMark
+}
+
+// hooksMark is the number of hooks of each kind registered at some point.
+type hooksMark struct {
+	beforeCommit  int
+	afterCommit   int
+	afterRollback int
+}
+
+// BeforeCommit registers fn to be called right before the root transaction is committed.
+// The function is called with the context of the transaction, so it can still execute statements within it.
+// If fn returns an error, the transaction is rolled back instead and the error is returned by Commit.
+func (tx *Transaction) BeforeCommit(fn func(ctx context.Context) error) {
+	tx.hooks.mu.Lock()
+	defer tx.hooks.mu.Unlock()
+
+	tx.hooks.beforeCommit = append(tx.hooks.beforeCommit, fn)
+}
+
+// AfterCommit registers fn to be called after the root transaction has been committed successfully.
+// The function is called with a context that no longer carries the transaction.
+func (tx *Transaction) AfterCommit(fn func(ctx context.Context)) {
+	tx.hooks.mu.Lock()
+	defer tx.hooks.mu.Unlock()
+
+	tx.hooks.afterCommit = append(tx.hooks.afterCommit, fn)
+}
+
+// AfterRollback registers fn to be called after the root transaction has been rolled back
+// or has failed to commit.
+// The function is called with a context that no longer carries the transaction.
+func (tx *Transaction) AfterRollback(fn func(ctx context.Context)) {
+	tx.hooks.mu.Lock()
+	defer tx.hooks.mu.Unlock()
+
+	tx.hooks.afterRollback = append(tx.hooks.afterRollback, fn)
+}
+
+// BeforeCommit registers fn to be called right before the transaction of the context is committed.
+// It returns ErrNoTransaction if the context doesn't have a transaction.
+// See Transaction.BeforeCommit for details.
+func (m *Manager) BeforeCommit(ctx context.Context, fn func(ctx context.Context) error) error {
+	tx, transacted := m.store.Transaction(ctx)
+	if !transacted {
+		return ErrNoTransaction
+	}
+
+	tx.BeforeCommit(fn)
+	return nil
+}
+
+// AfterCommit registers fn to be called after the transaction of the context has been committed.
+// It returns ErrNoTransaction if the context doesn't have a transaction.
+// See Transaction.AfterCommit for details.
+func (m *Manager) AfterCommit(ctx context.Context, fn func(ctx context.Context)) error {
+	tx, transacted := m.store.Transaction(ctx)
+	if !transacted {
+		return ErrNoTransaction
+	}
+
+	tx.AfterCommit(fn)
+	return nil
+}
+
+// AfterRollback registers fn to be called after the transaction of the context has been rolled back.
+// It returns ErrNoTransaction if the context doesn't have a transaction.
+// See Transaction.AfterRollback for details.
+func (m *Manager) AfterRollback(ctx context.Context, fn func(ctx context.Context)) error {
+	tx, transacted := m.store.Transaction(ctx)
+	if !transacted {
+		return ErrNoTransaction
+	}
+
+	tx.AfterRollback(fn)
+	return nil
+}
+
+// runBeforeCommit calls the registered before commit hooks until one of them fails.
+func (h *hooks) runBeforeCommit(ctx context.Context) error {
+	h.mu.Lock()
+	fns := h.beforeCommit
+	h.beforeCommit = nil
+	h.mu.Unlock()
+
+	for _, fn := range fns {
+		if err := fn(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runAfter calls the registered hooks for the outcome of the transaction.
+// Each hook is called at most once.
+func (h *hooks) runAfter(ctx context.Context, committed bool) {
+	h.mu.Lock()
+	fns := h.afterRollback
+	if committed {
+		fns = h.afterCommit
+	}
+	h.afterCommit = nil
+	h.afterRollback = nil
+	h.mu.Unlock()
+
+	for _, fn := range fns {
+		fn(ctx)
+	}
+}
+
+// markSavepoint records the hooks registered before the savepoint has been created.
+func (h *hooks) markSavepoint(savepoint string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.savepoints == nil {
+		h.savepoints = make(map[string]hooksMark)
+	}
+	h.savepoints[savepoint] = hooksMark{
+		beforeCommit:  len(h.beforeCommit),
+		afterCommit:   len(h.afterCommit),
+		afterRollback: len(h.afterRollback),
+	}
+}
+
+// rollbackToSavepoint drops the hooks registered after the savepoint has been created,
+// since the changes they have been registered for are discarded.
+func (h *hooks) rollbackToSavepoint(savepoint string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	mark, ok := h.savepoints[savepoint]
+	if !ok {
+		return
+	}
+	delete(h.savepoints, savepoint)
+
+	h.beforeCommit = h.beforeCommit[:min(mark.beforeCommit, len(h.beforeCommit))]
+	h.afterCommit = h.afterCommit[:min(mark.afterCommit, len(h.afterCommit))]
+	h.afterRollback = h.afterRollback[:min(mark.afterRollback, len(h.afterRollback))]
+}
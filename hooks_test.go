@@ -0,0 +1,170 @@
+package transact
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sklyar/go-transact/txsql"
+	"github.com/sklyar/go-transact/txtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHooksAfterCommit(t *testing.T) {
+	db := txtest.NewDB(t)
+	manager := &Manager{
+		db:    db,
+		store: newStore(),
+	}
+
+	baseContext := context.Background()
+	txContext := txtest.WithContext(baseContext)
+
+	tx := txtest.NewTx(t)
+	db.On("Begin", txContext, nilTxOptions).Return(tx, nil)
+	tx.On("Commit", txtest.MatchContext(txContext)).Return(nil)
+
+	var calls []string
+	err := manager.BeginFunc(baseContext, func(ctx context.Context) error {
+		assert.NoError(t, manager.BeforeCommit(ctx, func(ctx context.Context) error {
+			calls = append(calls, "before commit")
+			_, transacted := manager.store.Transaction(ctx)
+			assert.True(t, transacted, "Expected transaction to be active before commit")
+			return nil
+		}))
+		assert.NoError(t, manager.AfterRollback(ctx, func(_ context.Context) {
+			calls = append(calls, "after rollback")
+		}))
+
+		// hooks registered by a child fire on completion of the root transaction.
+		return manager.BeginFunc(ctx, func(ctx context.Context) error {
+			return manager.AfterCommit(ctx, func(ctx context.Context) {
+				calls = append(calls, "after commit")
+				_, transacted := manager.store.Transaction(ctx)
+				assert.False(t, transacted, "Expected no transaction after commit")
+			})
+		})
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"before commit", "after commit"}, calls)
+}
+
+func TestHooksBeforeCommitVetoesCommit(t *testing.T) {
+	db := txtest.NewDB(t)
+	manager := &Manager{
+		db:    db,
+		store: newStore(),
+	}
+
+	baseContext := context.Background()
+	txContext := txtest.WithContext(baseContext)
+
+	vetoErr := errors.New("veto")
+
+	tx := txtest.NewTx(t)
+	db.On("Begin", txContext, nilTxOptions).Return(tx, nil)
+	tx.On("Rollback", txtest.MatchContext(txContext)).Return(nil)
+
+	var calls []string
+	err := manager.BeginFunc(baseContext, func(ctx context.Context) error {
+		assert.NoError(t, manager.BeforeCommit(ctx, func(_ context.Context) error { return vetoErr }))
+		assert.NoError(t, manager.AfterCommit(ctx, func(_ context.Context) {
+			calls = append(calls, "after commit")
+		}))
+		assert.NoError(t, manager.AfterRollback(ctx, func(_ context.Context) {
+			calls = append(calls, "after rollback")
+		}))
+		return nil
+	})
+	assert.ErrorIs(t, err, vetoErr)
+	assert.Equal(t, []string{"after rollback"}, calls)
+	assert.Equal(t, 0, manager.store.Len())
+}
+
+func TestHooksAfterRollbackOnFailedCommit(t *testing.T) {
+	db := txtest.NewDB(t)
+	manager := &Manager{
+		db:    db,
+		store: newStore(),
+	}
+
+	baseContext := context.Background()
+	txContext := txtest.WithContext(baseContext)
+
+	commitErr := errors.New("commit error")
+
+	tx := txtest.NewTx(t)
+	db.On("Begin", txContext, nilTxOptions).Return(tx, nil)
+	tx.On("Commit", txtest.MatchContext(txContext)).Return(commitErr)
+
+	var calls []string
+	err := manager.BeginFunc(baseContext, func(ctx context.Context) error {
+		assert.NoError(t, manager.AfterCommit(ctx, func(_ context.Context) {
+			calls = append(calls, "after commit")
+		}))
+		assert.NoError(t, manager.AfterRollback(ctx, func(_ context.Context) {
+			calls = append(calls, "after rollback")
+		}))
+		return nil
+	})
+	assert.ErrorIs(t, err, commitErr)
+	assert.Equal(t, []string{"after rollback"}, calls)
+}
+
+func TestHooksWithoutTransaction(t *testing.T) {
+	manager := &Manager{store: newStore()}
+
+	ctx := context.Background()
+	assert.ErrorIs(t, manager.BeforeCommit(ctx, func(_ context.Context) error { return nil }), ErrNoTransaction)
+	assert.ErrorIs(t, manager.AfterCommit(ctx, func(_ context.Context) {}), ErrNoTransaction)
+	assert.ErrorIs(t, manager.AfterRollback(ctx, func(_ context.Context) {}), ErrNoTransaction)
+}
+
+func TestHooksDroppedOnRollbackToSavepoint(t *testing.T) {
+	db := txtest.NewDB(t)
+	manager := &Manager{
+		db:    db,
+		store: newStore(),
+	}
+
+	baseContext := context.Background()
+	txContext := txtest.WithContext(baseContext)
+	savepointTxContext := txtest.WithSavepointContext(txContext, "sp_1")
+
+	someErr := errors.New("some error")
+
+	tx := txtest.NewTx(t)
+	db.On("Begin", txContext, nilTxOptions).Return(tx, nil)
+	tx.On("Savepoint", txtest.MatchContext(savepointTxContext), "sp_1").Return(nil).Twice()
+	tx.On("RollbackToSavepoint", txtest.MatchContext(savepointTxContext), "sp_1").Return(nil)
+	tx.On("ReleaseSavepoint", txtest.MatchContext(savepointTxContext), "sp_1").Return(nil)
+	tx.On("Commit", txtest.MatchContext(txContext)).Return(nil)
+
+	var calls []string
+	hook := func(name string) func(ctx context.Context) error {
+		return func(_ context.Context) error {
+			calls = append(calls, name)
+			return nil
+		}
+	}
+
+	err := manager.BeginFunc(baseContext, func(ctx context.Context) error {
+		assert.NoError(t, manager.BeforeCommit(ctx, hook("root")))
+
+		// the hooks of the discarded savepoint scope, including its children, are dropped.
+		err := manager.BeginFunc(ctx, func(ctx context.Context) error {
+			assert.NoError(t, manager.BeforeCommit(ctx, hook("rolled back")))
+			assert.NoError(t, manager.BeginFunc(ctx, func(ctx context.Context) error {
+				return manager.BeforeCommit(ctx, hook("rolled back child"))
+			}))
+			return someErr
+		}, txsql.WithNested())
+		assert.ErrorIs(t, err, someErr)
+
+		return manager.BeginFunc(ctx, func(ctx context.Context) error {
+			return manager.BeforeCommit(ctx, hook("released"))
+		}, txsql.WithNested())
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"root", "released"}, calls)
+}
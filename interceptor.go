@@ -0,0 +1,94 @@
+package transact
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sklyar/go-transact/txsql"
+)
+
+// Operation is a transaction lifecycle operation.
+type Operation string
+
+// Operations seen by an Interceptor.
+const (
+	// OpBegin is the start of a transaction scope.
+	OpBegin Operation = "begin"
+	// OpCommit is the commit of a transaction scope.
+	OpCommit Operation = "commit"
+	// OpRollback is the rollback of a transaction scope.
+	OpRollback Operation = "rollback"
+)
+
+// Operations reported by a TxError in addition to the ones seen by an Interceptor.
+const (
+	// OpExec is the execution of a transaction function.
+	OpExec Operation = "exec"
+	// OpStore is the registration of a transaction in the transaction store.
+	OpStore Operation = "store"
+)
+
+// InterceptorInfo describes the operation seen by an Interceptor.
+type InterceptorInfo struct {
+	// Operation is the intercepted operation.
+	Operation Operation
+
+	// ID is the ID of the transaction.
+	ID string
+
+	// Depth is the nesting level of the transaction scope.
+	// The root transaction has depth 0.
+	Depth int
+
+	// Savepoint is the name of the savepoint backing the scope.
+	// It is empty for the root transaction and for the scopes that have joined their parent.
+	Savepoint string
+
+	// Options are the options the scope has been started with.
+	// They are nil if no options have been provided.
+	Options *txsql.TxOptions
+
+	// StartedAt is the time the root transaction has been started.
+	StartedAt time.Time
+
+	// Cause is the error the scope is rolled back for.
+	// It is nil for other operations and if the rollback has been requested explicitly.
+	Cause error
+}
+
+// errNotBegun is the error of the start of a scope whose interceptor has returned
+// without calling next and without an error.
+var errNotBegun = errors.New("interceptor did not begin the transaction")
+
+// Handler performs the intercepted operation.
+type Handler func(ctx context.Context) error
+
+// Interceptor intercepts the start, commit and rollback of every transaction scope.
+// It must call next to perform the operation and returns the outcome of the operation.
+// The context passed to next is used by the operation. For the start of a scope,
+// it also becomes the context of the transaction returned by Manager.Begin.
+// An interceptor rejecting the start of a scope must return an error; if it returns nil
+// without calling next, the start of the scope fails anyway.
+type Interceptor func(ctx context.Context, info InterceptorInfo, next Handler) error
+
+// WithInterceptors installs the interceptors on the manager.
+// The first interceptor is the outermost one, so it sees the operation first.
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(m *Manager) {
+		m.interceptors = append(m.interceptors, interceptors...)
+	}
+}
+
+// intercept performs the operation through the chain of interceptors.
+func intercept(ctx context.Context, interceptors []Interceptor, info InterceptorInfo, op Handler) error {
+	next := op
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, handler := interceptors[i], next
+		next = func(ctx context.Context) error {
+			return interceptor(ctx, info, handler)
+		}
+	}
+
+	return next(ctx)
+}
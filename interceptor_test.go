@@ -0,0 +1,162 @@
+package transact
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/sklyar/go-transact/txsql"
+	"github.com/sklyar/go-transact/txtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type interceptorKey struct{}
+
+func TestWithInterceptors(t *testing.T) {
+	mockDB := txtest.NewDB(t)
+	adapterFactory := func(_ TransactionStore) (txsql.DB, error) { return mockDB, nil }
+
+	var calls []string
+	recorder := func(name string) Interceptor {
+		return func(ctx context.Context, info InterceptorInfo, next Handler) error {
+			err := next(ctx)
+			calls = append(calls, fmt.Sprintf("%s: %s %s/%d: %v", name, info.Operation, info.ID, info.Depth, err))
+			return err
+		}
+	}
+
+	manager, _, err := NewManager(adapterFactory, WithInterceptors(recorder("outer"), recorder("inner")))
+	assert.NoError(t, err)
+
+	someErr := errors.New("some error")
+
+	tx := txtest.NewTx(t)
+	mockDB.On("Begin", mock.Anything, nilTxOptions).Return(tx, nil)
+	tx.On("Rollback", mock.Anything).Return(nil)
+
+	err = manager.BeginFunc(context.Background(), func(ctx context.Context) error {
+		return manager.BeginFunc(ctx, func(_ context.Context) error { return someErr })
+	})
+	assert.ErrorIs(t, err, someErr)
+	assert.Equal(t, []string{
+		"inner: begin 1/0: <nil>",
+		"outer: begin 1/0: <nil>",
+		"inner: begin 1/1: <nil>",
+		"outer: begin 1/1: <nil>",
+		"inner: rollback 1/1: <nil>",
+		"outer: rollback 1/1: <nil>",
+		"inner: rollback 1/0: <nil>",
+		"outer: rollback 1/0: <nil>",
+	}, calls)
+}
+
+func TestInterceptorContextIsUsedByTransaction(t *testing.T) {
+	db := txtest.NewDB(t)
+	manager := &Manager{
+		db:    db,
+		store: newStore(),
+		interceptors: []Interceptor{
+			func(ctx context.Context, info InterceptorInfo, next Handler) error {
+				if info.Operation == OpBegin {
+					ctx = context.WithValue(ctx, interceptorKey{}, "value")
+				}
+				return next(ctx)
+			},
+		},
+	}
+
+	tx := txtest.NewTx(t)
+	db.On("Begin", mock.Anything, nilTxOptions).Return(tx, nil)
+	tx.On("Commit", mock.Anything).Return(nil)
+
+	ctx, transaction, err := manager.Begin(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "value", ctx.Value(interceptorKey{}))
+
+	_, err = transaction.Commit(ctx)
+	assert.NoError(t, err)
+	tx.AssertCalled(t, "Commit", ctx)
+}
+
+func TestInterceptorFailureRollsBackTransaction(t *testing.T) {
+	db := txtest.NewDB(t)
+
+	policyErr := errors.New("policy violation")
+	manager := &Manager{
+		db:    db,
+		store: newStore(),
+		interceptors: []Interceptor{
+			func(ctx context.Context, info InterceptorInfo, next Handler) error {
+				if err := next(ctx); err != nil {
+					return err
+				}
+				if info.Operation == OpBegin {
+					return policyErr
+				}
+				return nil
+			},
+		},
+	}
+
+	tx := txtest.NewTx(t)
+	db.On("Begin", mock.Anything, nilTxOptions).Return(tx, nil)
+	tx.On("Rollback", mock.Anything).Return(nil)
+
+	_, _, err := manager.Begin(context.Background())
+	assert.ErrorIs(t, err, policyErr)
+	assert.Equal(t, 0, manager.store.Len())
+}
+
+func TestInterceptorSkippingBegin(t *testing.T) {
+	tests := []struct {
+		name string
+		// root is true if the skipped scope is the root one.
+		root bool
+		opts []txsql.TransactionOption
+	}{
+		{name: "root transaction", root: true},
+		{name: "joined scope"},
+		{name: "nested scope", opts: []txsql.TransactionOption{txsql.WithNested()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := txtest.NewDB(t)
+			tx := txtest.NewTx(t)
+
+			manager := &Manager{
+				db:    db,
+				store: newStore(),
+				interceptors: []Interceptor{
+					func(ctx context.Context, info InterceptorInfo, next Handler) error {
+						// the start of the scope is skipped without an error.
+						if info.Operation == OpBegin && (tt.root || info.Depth > 0) {
+							return nil
+						}
+						return next(ctx)
+					},
+				},
+			}
+
+			ctx := context.Background()
+			if !tt.root {
+				db.On("Begin", mock.Anything, nilTxOptions).Return(tx, nil)
+
+				var err error
+				ctx, _, err = manager.Begin(ctx)
+				require.NoError(t, err)
+			}
+
+			_, _, err := manager.Begin(ctx, tt.opts...)
+			assert.ErrorIs(t, err, errNotBegun)
+
+			var txErr *TxError
+			if assert.ErrorAs(t, err, &txErr) {
+				assert.Equal(t, OpBegin, txErr.Op)
+			}
+		})
+	}
+}
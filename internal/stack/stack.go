@@ -0,0 +1,47 @@
+// Package stack captures and formats the stacks reported by the manager and the adapters.
+package stack
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// maxDepth is the maximum number of frames of a captured stack.
+const maxDepth = 32
+
+// Callers returns the stack of the caller of Callers, skipping skip more frames.
+func Callers(skip int) []uintptr {
+	pcs := make([]uintptr, maxDepth)
+	n := runtime.Callers(skip+2, pcs)
+	return pcs[:n]
+}
+
+// Format formats the stack, skipping its leading frames for which internal returns true,
+// so the stack starts at the code of the user.
+func Format(pcs []uintptr, internal func(frame runtime.Frame) bool) string {
+	var b strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	skipping := true
+	for {
+		frame, more := frames.Next()
+		if skipping && internal(frame) {
+			if !more {
+				break
+			}
+			continue
+		}
+		skipping = false
+
+		b.WriteString(frame.Function)
+		b.WriteString("\n\t")
+		b.WriteString(frame.File)
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(frame.Line))
+		b.WriteByte('\n')
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
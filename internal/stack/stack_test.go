@@ -0,0 +1,44 @@
+package stack
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func capture() []uintptr {
+	return Callers(0)
+}
+
+func TestFormat(t *testing.T) {
+	pcs := capture()
+
+	tests := []struct {
+		name      string
+		internal  func(frame runtime.Frame) bool
+		wantFirst string
+	}{
+		{
+			name:      "nothing skipped",
+			internal:  func(runtime.Frame) bool { return false },
+			wantFirst: "stack.capture",
+		},
+		{
+			name:      "leading frames skipped",
+			internal:  func(frame runtime.Frame) bool { return strings.HasSuffix(frame.Function, "stack.capture") },
+			wantFirst: "stack.TestFormat",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatted := Format(pcs, tt.internal)
+
+			first, _, _ := strings.Cut(formatted, "\n")
+			assert.True(t, strings.HasSuffix(first, tt.wantFirst), first)
+			assert.Contains(t, formatted, "stack_test.go:")
+		})
+	}
+}
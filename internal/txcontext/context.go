@@ -16,8 +16,21 @@ const (
 type Value struct {
 	ID string
 
-	Child bool
-	Done  bool
+	// Depth is the nesting level of the transaction scope.
+	// The root transaction has depth 0.
+	Depth int
+	// Savepoint is the name of the savepoint backing a nested scope.
+	// It is empty if the scope joins its parent without a savepoint.
+	Savepoint string
+
+	Done bool
+
+	// Parent is the transaction scope that was suspended by this one.
+	// Once a root transaction is done, its parent scope becomes current again.
+	Parent *Value
+
+	// Owner is kept alive as long as a context of the transaction is reachable.
+	Owner any
 }
 
 // Wrap wraps the context with the transaction information.
@@ -39,36 +52,96 @@ func From(ctx context.Context) (Value, bool) {
 	return v, ok
 }
 
+// FromTx returns the transaction information of the scope with the given transaction ID.
+// Done transactions that suspended the scope are skipped, so the scope of a parent
+// transaction is found even after a new transaction started within it is done.
+// It returns false if the context doesn't have the transaction information.
+func FromTx(ctx context.Context, id string) (Value, bool) {
+	v, ok := From(ctx)
+	for ok && v.ID != id && v.Done && v.Depth == 0 && v.Parent != nil {
+		v = *v.Parent
+	}
+
+	return v, ok
+}
+
 // ID returns the transaction ID from the context.
 // It returns false if the context doesn't have the transaction ID.
+// If the transaction of the context is done, the ID of the suspended parent transaction is returned.
 func ID(ctx context.Context) (string, bool) {
-	v, ok := From(ctx)
+	v, ok := current(ctx)
 	if !ok {
 		return "", false
 	}
 
-	if v.Done {
+	if v.Done || v.ID == "" {
 		return "", false
 	}
 
 	return v.ID, true
 }
 
-// IsChild returns true if the context is a Child transaction.
-func IsChild(ctx context.Context) bool {
-	v, ok := From(ctx)
-	return ok && v.Child
+// Current returns the information of the transaction scope of the context that is in progress.
+// It returns false if the context doesn't have a transaction in progress.
+func Current(ctx context.Context) (Value, bool) {
+	v, ok := current(ctx)
+	if !ok || v.Done || v.ID == "" {
+		return Value{}, false
+	}
+
+	return v, true
 }
 
 // WithTx adds a transaction information to the context.
-// It returns a new context and a flag indicating whether the transaction is a Child.
+// If the context already has a transaction, the new scope is one level deeper.
+// It returns a new context and the information of the new scope.
 func WithTx(ctx context.Context, nextID func() string) (context.Context, Value) {
-	v, exists := From(ctx)
-	if exists {
-		v.Child = true
-	} else {
-		v.ID = nextID()
+	v, exists := current(ctx)
+	switch {
+	case exists && v.ID != "":
+		v.Depth++
+		v.Savepoint = ""
+	case exists:
+		// the context is suspended, so the new transaction becomes a root one.
+		parent := v
+		v = Value{ID: nextID(), Parent: &parent}
+	default:
+		v = Value{ID: nextID()}
 	}
 
 	return Wrap(ctx, v), v
 }
+
+// WithNewTx adds an information of a new root transaction to the context,
+// regardless of whether the context already has a transaction.
+// The existing transaction, if any, is suspended until the new one is done.
+func WithNewTx(ctx context.Context, nextID func() string) (context.Context, Value) {
+	v := Value{ID: nextID()}
+	if parent, exists := From(ctx); exists {
+		v.Parent = &parent
+	}
+
+	return Wrap(ctx, v), v
+}
+
+// Suspend hides the transaction of the context, if any.
+// The returned context is treated as a context without a transaction.
+func Suspend(ctx context.Context) context.Context {
+	var v Value
+	if parent, exists := From(ctx); exists {
+		v.Parent = &parent
+	}
+
+	return Wrap(ctx, v)
+}
+
+// current returns the information of the innermost transaction scope of the context
+// that is still in progress. Once a root transaction is done, its suspended parent is current.
+func current(ctx context.Context) (Value, bool) {
+	v, ok := From(ctx)
+	for ok && v.Done && v.Depth == 0 && v.Parent != nil {
+		v = *v.Parent
+	}
+
+	return v, ok
+}
@@ -11,7 +11,7 @@ func TestWrapContext(t *testing.T) {
 	ctx := context.Background()
 	value := Value{
 		ID:    "test",
-		Child: true,
+		Depth: 1,
 	}
 
 	t.Run("with non-nil context", func(t *testing.T) {
@@ -35,7 +35,7 @@ func TestFromContext(t *testing.T) {
 	ctx := context.Background()
 	value := Value{
 		ID:    "test",
-		Child: true,
+		Depth: 1,
 	}
 
 	t.Run("value in context", func(t *testing.T) {
@@ -57,7 +57,7 @@ func TestIDFromContext(t *testing.T) {
 	ctx := context.Background()
 	value := Value{
 		ID:    "test",
-		Child: true,
+		Depth: 1,
 	}
 
 	t.Run("ID present in context", func(t *testing.T) {
@@ -75,37 +75,6 @@ func TestIDFromContext(t *testing.T) {
 	})
 }
 
-func TestIsChildFromContext(t *testing.T) {
-	t.Run("Child present in context", func(t *testing.T) {
-		ctx := context.Background()
-		value := Value{
-			ID:    "test",
-			Child: true,
-		}
-		newCtx := Wrap(ctx, value)
-
-		isChild := IsChild(newCtx)
-		assert.True(t, isChild, "Expected Child to be true when inserted value is true")
-	})
-
-	t.Run("transaction not present in context", func(t *testing.T) {
-		isChild := IsChild(context.Background())
-		assert.False(t, isChild, "Expected Child to be false when no value is present")
-	})
-
-	t.Run("Child not present in context", func(t *testing.T) {
-		ctx := context.Background()
-		value := Value{
-			ID:    "test",
-			Child: false,
-		}
-		newCtx := Wrap(ctx, value)
-
-		isChild := IsChild(newCtx)
-		assert.False(t, isChild, "Expected Child to be false when inserted value is false")
-	})
-}
-
 func TestAddTxToContext(t *testing.T) {
 	t.Parallel()
 
@@ -123,14 +92,20 @@ func TestAddTxToContext(t *testing.T) {
 			name:         "add transaction info to empty context",
 			ctx:          emptyContext,
 			nextID:       "10",
-			want:         Wrap(emptyContext, Value{ID: "10", Child: false}),
-			wantCtxValue: Value{ID: "10", Child: false},
+			want:         Wrap(emptyContext, Value{ID: "10", Depth: 0}),
+			wantCtxValue: Value{ID: "10", Depth: 0},
 		},
 		{
 			name:         "add Child transaction info to transaction context",
-			ctx:          Wrap(emptyContext, Value{ID: "10", Child: false}),
-			want:         Wrap(emptyContext, Value{ID: "10", Child: true}),
-			wantCtxValue: Value{ID: "10", Child: true},
+			ctx:          Wrap(emptyContext, Value{ID: "10", Depth: 0}),
+			want:         Wrap(emptyContext, Value{ID: "10", Depth: 1}),
+			wantCtxValue: Value{ID: "10", Depth: 1},
+		},
+		{
+			name:         "add grandchild transaction info to savepoint context",
+			ctx:          Wrap(emptyContext, Value{ID: "10", Depth: 1, Savepoint: "sp_1"}),
+			want:         Wrap(emptyContext, Value{ID: "10", Depth: 2}),
+			wantCtxValue: Value{ID: "10", Depth: 2},
 		},
 	}
 
@@ -156,3 +131,57 @@ func assertContext(t *testing.T, ctx, expCtx context.Context) {
 	assert.Equal(t, ok1, ok2, "Expected both contexts to have same value")
 	assert.Equal(t, v1, v2, "Expected both contexts to have same value")
 }
+
+func TestWithNewTx(t *testing.T) {
+	ctx := Wrap(context.Background(), Value{ID: "10", Depth: 1, Savepoint: "sp_1"})
+
+	newCtx, v := WithNewTx(ctx, func() string { return "11" })
+	want := Value{ID: "11", Parent: &Value{ID: "10", Depth: 1, Savepoint: "sp_1"}}
+	assert.Equal(t, want, v, "Expected a root transaction value with the suspended parent")
+	assertContext(t, newCtx, Wrap(context.Background(), want))
+
+	id, ok := ID(newCtx)
+	assert.True(t, ok, "Expected ID to be present in context")
+	assert.Equal(t, "11", id, "Expected ID of the new transaction")
+
+	v.Done = true
+	id, ok = ID(Wrap(newCtx, v))
+	assert.True(t, ok, "Expected ID to be present in context")
+	assert.Equal(t, "10", id, "Expected ID of the parent transaction once the new one is done")
+	parent, _ := current(Wrap(newCtx, v))
+	assert.Equal(t, 1, parent.Depth, "Expected the parent scope to be current once the new one is done")
+}
+
+func TestSuspend(t *testing.T) {
+	ctx := Wrap(context.Background(), Value{ID: "10"})
+
+	suspended := Suspend(ctx)
+
+	id, ok := ID(suspended)
+	assert.False(t, ok, "Expected no ID to be present in suspended context")
+	assert.Equal(t, "", id, "Expected ID to be empty string")
+	scope, _ := current(suspended)
+	assert.Zero(t, scope.Depth, "Expected suspended context not to be a child")
+
+	_, v := WithTx(suspended, func() string { return "11" })
+	want := Value{ID: "11", Parent: &Value{Parent: &Value{ID: "10"}}}
+	assert.Equal(t, want, v, "Expected a new root transaction within suspended context")
+}
+
+func TestFromTx(t *testing.T) {
+	parent := Value{ID: "10"}
+	done := Value{ID: "11", Done: true, Parent: &parent}
+	ctx := Wrap(context.Background(), done)
+
+	v, ok := FromTx(ctx, "11")
+	assert.True(t, ok, "Expected value to be present in context")
+	assert.Equal(t, done, v, "Expected value of the done transaction")
+
+	v, ok = FromTx(ctx, "10")
+	assert.True(t, ok, "Expected value to be present in context")
+	assert.Equal(t, parent, v, "Expected value of the parent transaction")
+
+	v, ok = FromTx(context.Background(), "10")
+	assert.False(t, ok, "Expected no value to be present in context")
+	assert.Equal(t, Value{}, v, "Expected value to be default value")
+}
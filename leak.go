@@ -0,0 +1,109 @@
+package transact
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"time"
+)
+
+// errLeakedTransaction is the cause of the rollback of a leaked transaction.
+var errLeakedTransaction = errors.New("transaction has been leaked")
+
+// Leak describes a transaction that has not been completed.
+type Leak struct {
+	// ID is the ID of the transaction.
+	ID string
+
+	// Age is the time since the transaction has begun.
+	Age time.Duration
+
+	// Stack is the stack trace of the call that has begun the transaction.
+	// It is empty unless leak detection is enabled.
+	Stack string
+
+	// Collected is true if every context of the transaction has been garbage collected,
+	// so the transaction can never be completed by its owner.
+	Collected bool
+}
+
+// String returns a description of the leak.
+func (l Leak) String() string {
+	return fmt.Sprintf("transaction %s has been open for %s, begun at:\n%s", l.ID, l.Age, l.Stack)
+}
+
+// WithLeakDetection enables the detection of transactions that are never completed.
+// The manager captures the stack of every Begin call. Once every context of an open
+// transaction has been garbage collected, the transaction is reported to the report
+// function, if any, and rolled back, so its connection is returned to the pool.
+//
+// It is meant for debugging, since capturing the stacks slows down Begin.
+func WithLeakDetection(report func(leak Leak)) Option {
+	return func(m *Manager) {
+		m.detectLeaks = true
+		m.reportLeak = report
+	}
+}
+
+// trackLeak makes the transaction reported as leaked once the owner carried by its contexts
+// is garbage collected.
+func (m *Manager) trackLeak(o *owner, tx *Transaction) {
+	runtime.SetFinalizer(o, func(*owner) {
+		if tx.isCompleted() {
+			// a transaction rolled back by the watchdog waits in the store for its owner, which is gone.
+			_ = tx.detach()
+			return
+		}
+
+		if m.reportLeak != nil {
+			m.reportLeak(Leak{
+				ID:        tx.id,
+				Age:       time.Since(tx.startedAt),
+				Stack:     formatStack(tx.beginStack),
+				Collected: true,
+			})
+		}
+		// the finalizers run on a single goroutine, so the rollback,
+		// which runs the interceptors and the hooks, must not hold it up.
+		go func() {
+			tx.kill(context.Background(), errLeakedTransaction)
+			_ = tx.detach()
+		}()
+	})
+}
+
+// Leaks returns the transactions begun by the manager that are still open, oldest first.
+// The stacks of the transactions are captured only if leak detection is enabled.
+func (m *Manager) Leaks() []Leak {
+	txs := m.store.transactions()
+
+	leaks := make([]Leak, 0, len(txs))
+	for _, tx := range txs {
+		leaks = append(leaks, Leak{
+			ID:    tx.id,
+			Age:   time.Since(tx.startedAt),
+			Stack: formatStack(tx.beginStack),
+		})
+	}
+	return leaks
+}
+
+// TestingT is the subset of testing.TB used by VerifyNoLeaks.
+type TestingT interface {
+	Helper()
+	Cleanup(fn func())
+	Errorf(format string, args ...any)
+}
+
+// VerifyNoLeaks fails the test if any transaction begun by the manager
+// is still open when the test ends.
+func VerifyNoLeaks(t TestingT, m *Manager) {
+	t.Helper()
+	t.Cleanup(func() {
+		t.Helper()
+		for _, leak := range m.Leaks() {
+			t.Errorf("leaked %s", leak)
+		}
+	})
+}
@@ -0,0 +1,126 @@
+package transact
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/sklyar/go-transact/txsql"
+	"github.com/sklyar/go-transact/txtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeT struct {
+	cleanups []func()
+	errors   []string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Cleanup(fn func()) {
+	t.cleanups = append(t.cleanups, fn)
+}
+
+func (t *fakeT) Errorf(format string, args ...any) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func (t *fakeT) finish() {
+	for i := len(t.cleanups) - 1; i >= 0; i-- {
+		t.cleanups[i]()
+	}
+}
+
+func TestLeaks(t *testing.T) {
+	db := txtest.NewDB(t)
+	manager, _, err := NewManager(func(_ TransactionStore) (txsql.DB, error) { return db, nil }, WithLeakDetection(nil))
+	require.NoError(t, err)
+
+	tx := txtest.NewTx(t)
+	db.On("Begin", mock.Anything, nilTxOptions).Return(tx, nil)
+	tx.On("Commit", mock.Anything).Return(nil)
+	tx.On("Rollback", mock.Anything).Return(nil)
+
+	ft := new(fakeT)
+	VerifyNoLeaks(ft, manager)
+
+	leakedCtx, leaked, err := manager.Begin(context.Background())
+	require.NoError(t, err)
+	ctx, transaction, err := manager.Begin(context.Background())
+	require.NoError(t, err)
+
+	leaks := manager.Leaks()
+	require.Len(t, leaks, 2)
+	assert.Equal(t, "1", leaks[0].ID)
+	assert.Equal(t, "2", leaks[1].ID)
+	assert.Contains(t, leaks[0].Stack, "TestLeaks")
+	assert.False(t, leaks[0].Collected)
+
+	_, err = transaction.Commit(ctx)
+	require.NoError(t, err)
+
+	ft.finish()
+	require.Len(t, ft.errors, 1)
+	assert.Contains(t, ft.errors[0], "leaked transaction 1 has been open for")
+	assert.Contains(t, ft.errors[0], "TestLeaks")
+
+	_, err = leaked.Rollback(leakedCtx)
+	require.NoError(t, err)
+}
+
+func TestWithLeakDetectionReportsCollectedTransaction(t *testing.T) {
+	db := txtest.NewDB(t)
+
+	reported := make(chan Leak, 1)
+	manager, _, err := NewManager(func(_ TransactionStore) (txsql.DB, error) { return db, nil },
+		WithLeakDetection(func(leak Leak) { reported <- leak }))
+	require.NoError(t, err)
+
+	// the rollback blocks until the other finalizers have run.
+	release := make(chan struct{})
+	tx := txtest.NewTx(t)
+	db.On("Begin", mock.Anything, nilTxOptions).Return(tx, nil)
+	tx.On("Rollback", mock.Anything).Run(func(mock.Arguments) { <-release }).Return(nil).Once()
+
+	func() {
+		// the contexts of the transaction become unreachable, so it can never be completed.
+		_, _, err := manager.Begin(context.Background())
+		require.NoError(t, err)
+	}()
+
+	leak := awaitFinalizer(t, reported)
+	assert.Equal(t, "1", leak.ID)
+	assert.True(t, leak.Collected)
+	assert.Contains(t, leak.Stack, "TestWithLeakDetectionReportsCollectedTransaction")
+
+	// the rollback doesn't hold up the finalizer goroutine.
+	finalized := make(chan struct{}, 1)
+	func() {
+		runtime.SetFinalizer(new(owner), func(*owner) { finalized <- struct{}{} })
+	}()
+	awaitFinalizer(t, finalized)
+
+	close(release)
+	assert.Eventually(t, func() bool { return len(manager.Leaks()) == 0 }, 5*time.Second, 10*time.Millisecond)
+}
+
+// awaitFinalizer collects the garbage until a value is received from ch.
+func awaitFinalizer[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		runtime.GC()
+		select {
+		case v := <-ch:
+			return v
+		case <-timeout:
+			t.Fatal("finalizer has not run")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
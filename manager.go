@@ -4,9 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"runtime/debug"
 	"strconv"
 	"sync/atomic"
+	"time"
 
+	"github.com/sklyar/go-transact/internal/stack"
 	"github.com/sklyar/go-transact/internal/txcontext"
 	"github.com/sklyar/go-transact/txsql"
 )
@@ -28,23 +31,64 @@ type TransactionFunc func(tx context.Context) error
 
 // Manager is a type that helps manage transactions within a database.
 type Manager struct {
-	db    txsql.DB
-	store *store
+	db       txsql.DB
+	store    *store
+	counters counters
+
+	interceptors []Interceptor
+	metrics      Metrics
+	watchdog     *watchdog
+	tracer       txsql.Tracer
+
+	// profilerLabels is true if the transaction functions run with pprof labels.
+	profilerLabels bool
+
+	// detectLeaks is true if the transactions are tracked to detect leaks.
+	detectLeaks bool
+	reportLeak  func(leak Leak)
+
+	// reportNestingConflict is called instead of failing
+	// when a nested transaction requests incompatible options.
+	reportNestingConflict func(ctx context.Context, err error)
 
 	// lastID is the last transaction id.
 	// It is used to generate a new transaction id.
 	lastID uint64
 }
 
+// Option configures a Manager.
+type Option func(m *Manager)
+
 // NewManager creates a new transaction manager.
-func NewManager(adapterFactory AdapterFactoryFunc) (*Manager, txsql.DB, error) {
+func NewManager(adapterFactory AdapterFactoryFunc, opts ...Option) (*Manager, txsql.DB, error) {
 	store := newStore()
 	db, err := adapterFactory(store)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	return &Manager{db: db, store: store}, db, nil
+	m := &Manager{db: db, store: store}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	if m.watchdog != nil {
+		if err := m.watchdog.opts.validate(); err != nil {
+			return nil, nil, err
+		}
+		go m.watchdog.run(m.store)
+	}
+
+	return m, db, nil
+}
+
+// Close stops the background work of the manager, such as the watchdog.
+// It doesn't close the database nor complete the open transactions.
+func (m *Manager) Close() error {
+	if m.watchdog != nil {
+		m.watchdog.close()
+	}
+	return nil
 }
 
 // BeginFunc initiates a new transaction and executes a provided closure within it.
@@ -54,92 +98,413 @@ func NewManager(adapterFactory AdapterFactoryFunc) (*Manager, txsql.DB, error) {
 // If BeginFunc is invoked within an existing transaction, it reuses the parent transaction instead of
 // creating a new one. This way, the function provides transaction nesting capabilities. In case the
 // 'child' transaction encounters an error, it will also mark the parent transaction for rollback.
+// With txsql.WithNested, the 'child' transaction runs inside a savepoint instead: an error rolls back
+// to the savepoint only, and the parent transaction can recover and keep going.
+//
+// If the child requests options the parent cannot provide, such as a stricter isolation level,
+// ErrIncompatibleTransaction is returned, unless the manager is created with WithLenientNesting.
 //
 // Note that the actual (parent) transaction does not complete after the child transaction finishes, but only upon
 // completion of the parent transaction itself. This strategy ensures the atomicity of grouped operations.
-func (m *Manager) BeginFunc(ctx context.Context, fn TransactionFunc, opts ...txsql.TransactionOption) (err error) {
-	ctx, tx, err := m.transaction(ctx, opts)
+//
+// The nesting behavior can be changed with txsql.WithPropagation. Depending on the propagation mode,
+// the closure may run in a new independent transaction or without a transaction at all.
+//
+// If the closure panics, the transaction is rolled back and the panic is propagated with the original value.
+// A child transaction that joined its parent is only marked for rollback, so the parent cannot commit it.
+// With txsql.WithPanicAsError, the panic is returned as a *PanicError instead.
+//
+// If ctx is canceled while the closure runs, the root transaction is rolled back right away,
+// and the returned error wraps the error of ctx.
+//
+// With txsql.WithTimeout, the closure receives a context with the deadline of the transaction.
+// If the deadline passes, the transaction is rolled back and the error wraps ErrTransactionTimeout.
+//
+// With txsql.WithRetry, a root transaction that fails with a retryable error is rolled back
+// and the closure is executed again in a fresh transaction. Nested scopes are never retried.
+//
+// If the closure fails or the transaction fails to commit, the returned error is a *TxError
+// telling the failed operation apart from the error of the closure.
+func (m *Manager) BeginFunc(ctx context.Context, fn TransactionFunc, opts ...txsql.TransactionOption) error {
+	txOptions := newTxOptions(opts)
+	if txOptions != nil && txOptions.Retry != nil && m.startsRoot(ctx, txOptions) {
+		return m.beginFuncWithRetry(ctx, fn, txOptions)
+	}
+
+	return m.beginFunc(ctx, fn, txOptions)
+}
+
+func (m *Manager) beginFunc(ctx context.Context, fn TransactionFunc, txOptions *txsql.TxOptions) (err error) {
+	startedAt := time.Now()
+
+	parent := ctx
+	ctx, tx, err := m.transaction(ctx, txOptions)
 	if err != nil {
 		return err
 	}
 
+	if v, _ := txcontext.FromTx(ctx, tx.id); !tx.empty && v.Depth == 0 {
+		stop := tx.rollbackOnCancel(parent)
+		defer stop()
+	}
+
+	// fail returns the error of the operation, rolling the transaction back if needed.
+	fail := func(op Operation, err error, rollback func(cause error) error) error {
+		v, _ := txcontext.FromTx(ctx, tx.id)
+		txErr := &TxError{
+			Op:       op,
+			ID:       tx.id,
+			Depth:    v.Depth,
+			Duration: time.Since(startedAt),
+			Err:      err,
+		}
+		if rollback == nil {
+			return txErr
+		}
+
+		if rerr := rollback(txErr); rerr != nil {
+			// the cause seen by the rollback stays unchanged.
+			withRollback := *txErr
+			withRollback.RollbackErr = rerr
+			return &withRollback
+		}
+		return txErr
+	}
+
 	defer func() {
-		if derr := m.store.Delete(ctx, tx); derr != nil {
-			err = errors.Join(err, fmt.Errorf("failed to delete transaction from context: %w", derr))
+		p := recover()
+		if p == nil {
 			return
 		}
+
+		panicErr := &PanicError{Value: p, Stack: debug.Stack()}
+		if txOptions == nil || !txOptions.PanicAsError {
+			_ = tx.abort(ctx, panicErr)
+			panic(p)
+		}
+
+		err = fail(OpExec, panicErr, func(_ error) error {
+			return tx.abort(ctx, panicErr)
+		})
 	}()
 
-	if err = fn(ctx); err != nil {
-		err = fmt.Errorf("failed to execute transaction function: %w", err)
-		if _, rerr := tx.Rollback(ctx); rerr != nil {
-			err = errors.Join(err, fmt.Errorf("failed to rollback transaction: %w", rerr))
+	if err = m.run(ctx, tx, fn); err != nil {
+		if errors.Is(context.Cause(ctx), ErrTransactionTimeout) && !errors.Is(err, ErrTransactionTimeout) {
+			err = fmt.Errorf("%w: %w", ErrTransactionTimeout, err)
 		}
-		return err
+		if cerr := parent.Err(); cerr != nil && !errors.Is(err, cerr) {
+			err = fmt.Errorf("%w: %w", cerr, err)
+		}
+		return fail(OpExec, err, func(cause error) error {
+			_, rerr := tx.rollbackFor(ctx, cause)
+			return rerr
+		})
 	}
 
 	if _, err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		if cerr := parent.Err(); cerr != nil && !errors.Is(err, cerr) {
+			err = fmt.Errorf("%w: %w", cerr, err)
+		}
+		return fail(OpCommit, err, nil)
 	}
 
 	return nil
 }
 
+// Do executes fn within a transaction and returns the value produced by fn.
+// It has the same commit and rollback semantics as Manager.BeginFunc.
+// If the transaction fails, the zero value of T is returned along with the error,
+// so a value built within a rolled back transaction never leaks out.
+func Do[T any](ctx context.Context, m *Manager, fn func(ctx context.Context) (T, error), opts ...txsql.TransactionOption) (T, error) {
+	var result T
+	err := m.BeginFunc(ctx, func(ctx context.Context) error {
+		v, err := fn(ctx)
+		if err != nil {
+			return err
+		}
+
+		result = v
+		return nil
+	}, opts...)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return result, nil
+}
+
 // Begin initiates a new transaction, providing manual control over the transaction's lifecycle.
 // It returns a Transaction object that can be used to commit or rollback the transaction,
 // as well as a context that carries the transaction ID.
 //
 // Like BeginFunc, if Begin is invoked within an existing transaction, it reuses the parent
 // transaction instead of creating a new one, enabling transaction nesting.
+// The nesting behavior can be changed with txsql.WithPropagation. If the scope turns out
+// to be non-transactional, committing or rolling back the returned Transaction does nothing.
 //
 // It's important to note that this method doesn't automatically handle committing or rollback
 // the transaction - these operations must be explicitly invoked on the returned Transaction.
+// With txsql.WithTimeout, the transaction is rolled back once the deadline passes,
+// and committing it afterwards returns ErrTransactionTimeout.
 func (m *Manager) Begin(ctx context.Context, opts ...txsql.TransactionOption) (context.Context, *Transaction, error) {
-	return m.transaction(ctx, opts)
+	return m.transaction(ctx, newTxOptions(opts))
+}
+
+func (m *Manager) transaction(ctx context.Context, txOptions *txsql.TxOptions) (context.Context, *Transaction, error) {
+	var propagation txsql.Propagation
+	if txOptions != nil {
+		propagation = txOptions.Propagation
+	}
+
+	_, active := txcontext.ID(ctx)
+
+	switch propagation {
+	case txsql.PropagationRequired, txsql.PropagationNested:
+		return m.join(ctx, txOptions)
+	case txsql.PropagationRequiresNew:
+		ctx, _ = txcontext.WithNewTx(ctx, m.nextID)
+		return m.begin(ctx, txOptions)
+	case txsql.PropagationSupports:
+		if !active {
+			return ctx, newEmptyTransaction(), nil
+		}
+		return m.join(ctx, txOptions)
+	case txsql.PropagationMandatory:
+		if !active {
+			return nil, nil, m.refuse(ctx, txOptions, ErrTransactionRequired)
+		}
+		return m.join(ctx, txOptions)
+	case txsql.PropagationNotSupported:
+		if active {
+			ctx = txcontext.Suspend(ctx)
+		}
+		return ctx, newEmptyTransaction(), nil
+	case txsql.PropagationNever:
+		if active {
+			return nil, nil, m.refuse(ctx, txOptions, ErrTransactionNotAllowed)
+		}
+		return ctx, newEmptyTransaction(), nil
+	default:
+		return nil, nil, fmt.Errorf("unknown transaction propagation: %d", propagation)
+	}
 }
 
-func (m *Manager) transaction(ctx context.Context, opts []txsql.TransactionOption) (context.Context, *Transaction, error) {
-	ctx, ctxVal := txcontext.WithTx(ctx, m.nextID)
+// refuse fails the start of a scope in the context with err.
+// The failure goes through the interceptors, so they observe it like any other failure
+// of the start of a scope, but they cannot turn it into a success.
+func (m *Manager) refuse(ctx context.Context, txOptions *txsql.TxOptions, err error) error {
+	info := InterceptorInfo{
+		Operation: OpBegin,
+		Options:   txOptions,
+		StartedAt: time.Now(),
+	}
+	if v, active := txcontext.Current(ctx); active {
+		// the refused scope would have been nested in the scope of the context.
+		info.ID, info.Depth = v.ID, v.Depth+1
+		if tx, transacted := m.store.Transaction(ctx); transacted {
+			info.StartedAt = tx.startedAt
+		}
+	}
+
+	if ierr := intercept(ctx, m.interceptors, info, func(context.Context) error { return err }); ierr != nil {
+		return ierr
+	}
+	return err
+}
+
+// startsRoot reports whether a transaction with the given options
+// would begin a new database transaction in the context.
+func (m *Manager) startsRoot(ctx context.Context, txOptions *txsql.TxOptions) bool {
+	_, active := txcontext.ID(ctx)
+	switch txOptions.Propagation {
+	case txsql.PropagationRequired, txsql.PropagationNested:
+		return !active
+	case txsql.PropagationRequiresNew:
+		return true
+	default:
+		return false
+	}
+}
+
+// join joins the transaction of the context or begins a new one if there is none.
+// If the propagation is txsql.PropagationNested, the joined scope is backed by a savepoint.
+// The options of the joined scope must be compatible with the options of the transaction.
+func (m *Manager) join(ctx context.Context, txOptions *txsql.TxOptions) (context.Context, *Transaction, error) {
+	parent := ctx
+	ctx, ctxVal := txcontext.WithTx(parent, m.nextID)
 	if ctxVal.Done {
 		return nil, nil, errors.New("transaction already done")
 	}
-	if ctxVal.Child {
-		tx, transacted := m.store.Transaction(ctx)
-		if !transacted {
-			return nil, nil, errors.New("failed to find parent transaction")
-		}
+	if ctxVal.Depth == 0 {
+		return m.begin(ctx, txOptions)
+	}
 
-		return ctx, tx, nil
+	tx, transacted := m.store.Transaction(ctx)
+	if !transacted {
+		return nil, nil, m.refuse(parent, txOptions, errors.New("failed to find parent transaction"))
 	}
 
-	var txOptions *txsql.TxOptions
-	if len(opts) > 0 {
-		txOptions = new(txsql.TxOptions)
+	if err := m.checkNesting(ctx, tx.options, txOptions); err != nil {
+		return nil, nil, m.refuse(parent, txOptions, err)
 	}
-	for _, opt := range opts {
-		opt(txOptions)
+
+	nested := txOptions != nil && txOptions.Propagation == txsql.PropagationNested
+	if nested {
+		ctxVal.Savepoint = savepointName(ctxVal.Depth)
+		ctx = txcontext.Wrap(parent, ctxVal)
 	}
 
-	sqlTx, err := m.db.Begin(ctx, txOptions)
+	info := InterceptorInfo{
+		Operation: OpBegin,
+		ID:        ctxVal.ID,
+		Depth:     ctxVal.Depth,
+		Savepoint: ctxVal.Savepoint,
+		Options:   txOptions,
+		StartedAt: tx.startedAt,
+	}
+	begun := false
+	err := intercept(ctx, m.interceptors, info, func(ictx context.Context) error {
+		ctx = ictx
+		if !nested {
+			begun = true
+			return nil
+		}
+
+		if err := tx.Savepoint(ctx, ctxVal.Savepoint); err != nil {
+			return &TxError{Op: OpBegin, ID: ctxVal.ID, Depth: ctxVal.Depth, Err: err}
+		}
+		tx.hooks.markSavepoint(ctxVal.Savepoint)
+		begun = true
+		return nil
+	})
+	if err == nil && !begun {
+		err = &TxError{Op: OpBegin, ID: ctxVal.ID, Depth: ctxVal.Depth, Err: errNotBegun}
+	}
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+		return nil, nil, err
 	}
+	tx.enterScope()
+
+	return ctx, tx, nil
+}
 
+// begin begins a new database transaction for the root scope of the context.
+func (m *Manager) begin(ctx context.Context, txOptions *txsql.TxOptions) (context.Context, *Transaction, error) {
 	tid, _ := txcontext.ID(ctx)
-	tx := newTransaction(tid, sqlTx)
 
-	if err := m.store.Add(tx); err != nil {
-		addErr := fmt.Errorf("failed to add transaction: %w", err)
-		ctx, err := tx.Rollback(ctx)
+	var cancel context.CancelFunc
+	if txOptions != nil && txOptions.Timeout > 0 {
+		ctx, cancel = context.WithTimeoutCause(ctx, txOptions.Timeout, ErrTransactionTimeout)
+	}
+
+	if m.tracer != nil {
+		ctx = m.tracer.StartTransaction(ctx, txsql.TransactionInfo{ID: tid, Options: txOptions})
+	}
+	traceCtx := ctx
+
+	var tx *Transaction
+	info := InterceptorInfo{
+		Operation: OpBegin,
+		ID:        tid,
+		Options:   txOptions,
+		StartedAt: time.Now(),
+	}
+	err := intercept(ctx, m.interceptors, info, func(ictx context.Context) error {
+		ctx = ictx
+
+		sqlTx, err := m.db.Begin(ctx, txOptions)
 		if err != nil {
-			return ctx, nil, errors.Join(addErr, fmt.Errorf("failed to rollback transaction: %w", err))
+			return &TxError{Op: OpBegin, ID: tid, Duration: time.Since(info.StartedAt), Err: err}
+		}
+
+		// the transaction is set up completely before it is added to the store,
+		// since the watchdog and the leak detection may complete it from then on.
+		tx = newTransaction(tid, sqlTx)
+		tx.options = txOptions
+		tx.startedAt = info.StartedAt
+		if m.watchdog != nil {
+			tx.watched = true
+		}
+		if m.watchdog != nil || m.detectLeaks {
+			tx.beginStack = stack.Callers(0)
+		}
+		tx.store = m.store
+		tx.interceptors = m.interceptors
+		tx.counters = &m.counters
+		tx.tracer = m.tracer
+		tx.traceCtx = traceCtx
+		tx.cancelTimeout = cancel
+		if m.metrics != nil {
+			tx.metrics = m.metrics
+			m.metrics.TransactionBegun()
+		}
+
+		if err := m.store.Add(tx); err != nil {
+			// the transaction has not been added, so it is rolled back on its own,
+			// without removing the transaction of the same ID from the store.
+			tx.store, tx.interceptors, tx.counters, tx.tracer = nil, nil, nil, nil
+			addErr := &TxError{Op: OpStore, ID: tid, Duration: time.Since(info.StartedAt), Err: err}
+			_, rerr := tx.rollbackFor(ctx, addErr)
+			tx = nil
+			if rerr != nil {
+				return &TxError{Op: OpStore, ID: tid, Duration: addErr.Duration, Err: err, RollbackErr: rerr}
+			}
+			return addErr
+		}
+
+		return nil
+	})
+	if err == nil && tx == nil {
+		err = &TxError{Op: OpBegin, ID: tid, Duration: time.Since(info.StartedAt), Err: errNotBegun}
+	}
+	if err != nil {
+		if tx != nil {
+			// the transaction has begun, but an interceptor has failed.
+			if _, rerr := tx.rollbackFor(ctx, err); rerr != nil {
+				err = errors.Join(err, &TxError{Op: OpRollback, ID: tid, Duration: time.Since(info.StartedAt), Err: rerr})
+			}
+		}
+		if cancel != nil {
+			cancel()
 		}
-		return ctx, nil, addErr
+		if m.tracer != nil && tx == nil {
+			// a begun transaction has ended its trace on rollback.
+			m.tracer.EndTransaction(traceCtx, false, err)
+		}
+		return nil, nil, err
+	}
+
+	if cancel != nil {
+		tx.watchTimeout(ctx)
+	}
+	ctx, o := withOwner(ctx, tx)
+	if m.detectLeaks {
+		m.trackLeak(o, tx)
 	}
 
 	return ctx, tx, nil
 }
 
+// newTxOptions applies the options to new TxOptions.
+// It returns nil if there are no options.
+func newTxOptions(opts []txsql.TransactionOption) *txsql.TxOptions {
+	if len(opts) == 0 {
+		return nil
+	}
+
+	txOptions := new(txsql.TxOptions)
+	for _, opt := range opts {
+		opt(txOptions)
+	}
+	return txOptions
+}
+
+// savepointName returns the name of the savepoint for the given nesting depth.
+func savepointName(depth int) string {
+	return "sp_" + strconv.Itoa(depth)
+}
+
 // nextID returns the next transaction id.
 func (m *Manager) nextID() string {
 	id := atomic.AddUint64(&m.lastID, 1)
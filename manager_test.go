@@ -2,12 +2,17 @@ package transact
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/sklyar/go-transact/txsql"
 	"github.com/sklyar/go-transact/txtest"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 var nilTxOptions = (*txsql.TxOptions)(nil)
@@ -50,7 +55,7 @@ func TestBeginFunc(t *testing.T) {
 	txContext := txtest.WithContext(baseContext)
 
 	tx := txtest.NewTx(t)
-	tx.On("Commit", txContext).Return(nil)
+	tx.On("Commit", txtest.MatchContext(txContext)).Return(nil)
 	db.On("Begin", txContext, nilTxOptions).Return(tx, nil)
 
 	err := manager.BeginFunc(baseContext, func(_ context.Context) error { return nil })
@@ -69,7 +74,7 @@ func TestBeginFuncTransactionFunctionReturnsError(t *testing.T) {
 	txContext := txtest.WithContext(baseContext)
 
 	tx := txtest.NewTx(t)
-	tx.On("Rollback", txContext).Return(nil)
+	tx.On("Rollback", txtest.MatchContext(txContext)).Return(nil)
 	db.On("Begin", txContext, nilTxOptions).Return(tx, nil)
 
 	someErr := errors.New("some error")
@@ -80,6 +85,79 @@ func TestBeginFuncTransactionFunctionReturnsError(t *testing.T) {
 	assert.Equal(t, 0, manager.store.Len())
 }
 
+func TestBeginFuncTxError(t *testing.T) {
+	someErr := errors.New("some error")
+	rollbackErr := errors.New("rollback error")
+
+	tests := []struct {
+		name  string
+		fnErr error
+		setup func(tx *txtest.Tx)
+
+		wantOp          Operation
+		wantErr         error
+		wantRollbackErr error
+	}{
+		{
+			name:  "function fails",
+			fnErr: someErr,
+			setup: func(tx *txtest.Tx) {
+				tx.On("Rollback", mock.Anything).Return(nil)
+			},
+			wantOp:  OpExec,
+			wantErr: someErr,
+		},
+		{
+			name:  "function and rollback fail",
+			fnErr: someErr,
+			setup: func(tx *txtest.Tx) {
+				tx.On("Rollback", mock.Anything).Return(rollbackErr)
+			},
+			wantOp:          OpExec,
+			wantErr:         someErr,
+			wantRollbackErr: rollbackErr,
+		},
+		{
+			name: "commit fails",
+			setup: func(tx *txtest.Tx) {
+				tx.On("Commit", mock.Anything).Return(someErr)
+			},
+			wantOp:  OpCommit,
+			wantErr: someErr,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			db := txtest.NewDB(t)
+			manager := &Manager{
+				db:    db,
+				store: newStore(),
+			}
+
+			tx := txtest.NewTx(t)
+			tt.setup(tx)
+			db.On("Begin", mock.Anything, nilTxOptions).Return(tx, nil)
+
+			err := manager.BeginFunc(context.Background(), func(_ context.Context) error {
+				return tt.fnErr
+			})
+
+			var txErr *TxError
+			assert.ErrorAs(t, err, &txErr)
+			assert.Equal(t, tt.wantOp, txErr.Op)
+			assert.Equal(t, "1", txErr.ID)
+			assert.Equal(t, 0, txErr.Depth)
+			assert.Positive(t, txErr.Duration)
+			assert.ErrorIs(t, txErr.Err, tt.wantErr)
+			assert.Equal(t, tt.wantRollbackErr, txErr.RollbackErr)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
 func TestBeginFuncSuccessfulTransactionWithChildTransaction(t *testing.T) {
 	db := txtest.NewDB(t)
 	manager := &Manager{
@@ -93,9 +171,9 @@ func TestBeginFuncSuccessfulTransactionWithChildTransaction(t *testing.T) {
 
 	tx := txtest.NewTx(t)
 	db.On("Begin", txContext, nilTxOptions).Return(tx, nil)
-	db.On("Query", txContext, "SELECT 1").Return(nil, nil)
-	db.On("Query", childTxContext, "SELECT 2").Return(nil, nil)
-	tx.On("Commit", txContext).Return(nil)
+	db.On("Query", txtest.MatchContext(txContext), "SELECT 1").Return(nil, nil)
+	db.On("Query", txtest.MatchContext(childTxContext), "SELECT 2").Return(nil, nil)
+	tx.On("Commit", txtest.MatchContext(txContext)).Return(nil)
 
 	err := manager.BeginFunc(ctx, func(tx context.Context) error {
 		_, err := db.Query(tx, "SELECT 1")
@@ -125,9 +203,9 @@ func TestBeginFuncErrorOnChildTransactionRetrieval(t *testing.T) {
 
 	tx := txtest.NewTx(t)
 	db.On("Begin", txContext, nilTxOptions).Return(tx, nil)
-	db.On("Query", txContext, "SELECT 1").Return(nil, nil)
-	db.On("Query", childTxContext, "SELECT 2").Return(nil, someErr)
-	tx.On("Rollback", childTxContext).Return(nil)
+	db.On("Query", txtest.MatchContext(txContext), "SELECT 1").Return(nil, nil)
+	db.On("Query", txtest.MatchContext(childTxContext), "SELECT 2").Return(nil, someErr)
+	tx.On("Rollback", txtest.MatchContext(childTxContext)).Return(nil)
 
 	err := manager.BeginFunc(ctx, func(tx context.Context) error {
 		_, err := db.Query(tx, "SELECT 1")
@@ -141,3 +219,613 @@ func TestBeginFuncErrorOnChildTransactionRetrieval(t *testing.T) {
 	assert.ErrorContains(t, err, someErr.Error())
 	assert.Equal(t, 0, manager.store.Len())
 }
+
+func TestBeginFuncNestedTransactionRollsBackToSavepoint(t *testing.T) {
+	db := txtest.NewDB(t)
+	manager := &Manager{
+		db:    db,
+		store: newStore(),
+	}
+
+	ctx := context.Background()
+	txContext := txtest.WithContext(ctx)
+	savepointTxContext := txtest.WithSavepointContext(txContext, "sp_1")
+
+	someErr := errors.New("some error")
+
+	tx := txtest.NewTx(t)
+	db.On("Begin", txContext, nilTxOptions).Return(tx, nil)
+	tx.On("Savepoint", txtest.MatchContext(savepointTxContext), "sp_1").Return(nil)
+	db.On("Query", txtest.MatchContext(savepointTxContext), "SELECT 1").Return(nil, someErr)
+	tx.On("RollbackToSavepoint", txtest.MatchContext(savepointTxContext), "sp_1").Return(nil)
+	tx.On("ReleaseSavepoint", txtest.MatchContext(savepointTxContext), "sp_1").Return(nil)
+	db.On("Query", txtest.MatchContext(txContext), "SELECT 2").Return(nil, nil)
+	tx.On("Commit", txtest.MatchContext(txContext)).Return(nil)
+
+	err := manager.BeginFunc(ctx, func(tx context.Context) error {
+		err := manager.BeginFunc(tx, func(tx context.Context) error {
+			_, err := db.Query(tx, "SELECT 1")
+			return err
+		}, txsql.WithNested())
+		assert.ErrorIs(t, err, someErr)
+
+		// the parent transaction recovers and keeps going.
+		_, err = db.Query(tx, "SELECT 2")
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, manager.store.Len())
+}
+
+func TestBeginFuncNestedTransactionReleasesSavepoint(t *testing.T) {
+	db := txtest.NewDB(t)
+	manager := &Manager{
+		db:    db,
+		store: newStore(),
+	}
+
+	ctx := context.Background()
+	txContext := txtest.WithContext(ctx)
+	savepointTxContext := txtest.WithSavepointContext(txContext, "sp_1")
+
+	tx := txtest.NewTx(t)
+	db.On("Begin", txContext, nilTxOptions).Return(tx, nil)
+	tx.On("Savepoint", txtest.MatchContext(savepointTxContext), "sp_1").Return(nil)
+	tx.On("ReleaseSavepoint", txtest.MatchContext(savepointTxContext), "sp_1").Return(nil)
+	tx.On("Commit", txtest.MatchContext(txContext)).Return(nil)
+
+	err := manager.BeginFunc(ctx, func(tx context.Context) error {
+		return manager.BeginFunc(tx, func(_ context.Context) error { return nil }, txsql.WithNested())
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, manager.store.Len())
+}
+
+func TestBeginPropagation(t *testing.T) {
+	t.Parallel()
+
+	baseContext := context.Background()
+	txContext := txtest.WithContext(baseContext)
+
+	tests := []struct {
+		name        string
+		propagation txsql.Propagation
+		inTx        bool
+
+		wantBegin bool
+		wantEmpty bool
+		wantErr   error
+	}{
+		{name: "required without transaction", propagation: txsql.PropagationRequired, wantBegin: true},
+		{name: "required within transaction", propagation: txsql.PropagationRequired, inTx: true},
+		{name: "requires new without transaction", propagation: txsql.PropagationRequiresNew, wantBegin: true},
+		{name: "requires new within transaction", propagation: txsql.PropagationRequiresNew, inTx: true, wantBegin: true},
+		{name: "supports without transaction", propagation: txsql.PropagationSupports, wantEmpty: true},
+		{name: "supports within transaction", propagation: txsql.PropagationSupports, inTx: true},
+		{name: "mandatory without transaction", propagation: txsql.PropagationMandatory, wantErr: ErrTransactionRequired},
+		{name: "mandatory within transaction", propagation: txsql.PropagationMandatory, inTx: true},
+		{name: "not supported without transaction", propagation: txsql.PropagationNotSupported, wantEmpty: true},
+		{name: "not supported within transaction", propagation: txsql.PropagationNotSupported, inTx: true, wantEmpty: true},
+		{name: "never without transaction", propagation: txsql.PropagationNever, wantEmpty: true},
+		{name: "never within transaction", propagation: txsql.PropagationNever, inTx: true, wantErr: ErrTransactionNotAllowed},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			db := txtest.NewDB(t)
+			manager := &Manager{
+				db:    db,
+				store: newStore(),
+			}
+
+			parentTx := &Transaction{id: "1", Tx: txtest.NewTx(t)}
+			ctx := baseContext
+			if tt.inTx {
+				ctx = txContext
+				// ensure the next transaction ID differs from the parent one.
+				manager.lastID = 1
+				assert.NoError(t, manager.store.Add(parentTx))
+			}
+
+			txOptions := &txsql.TxOptions{Propagation: tt.propagation}
+			if tt.wantBegin {
+				db.On("Begin", mock.Anything, txOptions).Return(txtest.NewTx(t), nil)
+			}
+
+			ctx, tx, err := manager.Begin(ctx, txsql.WithPropagation(tt.propagation))
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+
+			ctxTx, transacted := manager.store.Transaction(ctx)
+			switch {
+			case tt.wantEmpty:
+				assert.True(t, tx.empty)
+				assert.False(t, transacted, "Expected no transaction in the context")
+			case tt.wantBegin:
+				assert.NotSame(t, parentTx, tx)
+				assert.Same(t, tx, ctxTx)
+			default:
+				assert.Same(t, parentTx, tx)
+				assert.Same(t, parentTx, ctxTx)
+			}
+		})
+	}
+}
+
+func TestBeginFuncRequiresNewWithinTransaction(t *testing.T) {
+	db := txtest.NewDB(t)
+	manager := &Manager{
+		db:    db,
+		store: newStore(),
+	}
+
+	ctx := context.Background()
+	txContext := txtest.WithContext(ctx)
+
+	someErr := errors.New("some error")
+
+	outerTx := txtest.NewTx(t)
+	innerTx := txtest.NewTx(t)
+	db.On("Begin", txContext, nilTxOptions).Return(outerTx, nil)
+	db.On("Begin", mock.Anything, &txsql.TxOptions{Propagation: txsql.PropagationRequiresNew}).Return(innerTx, nil)
+	innerTx.On("Commit", mock.Anything).Return(nil)
+	outerTx.On("Rollback", txtest.MatchContext(txContext)).Return(nil)
+
+	err := manager.BeginFunc(ctx, func(ctx context.Context) error {
+		innerCtx, inner, err := manager.Begin(ctx, txsql.WithPropagation(txsql.PropagationRequiresNew))
+		assert.NoError(t, err)
+		assert.Equal(t, "2", inner.ID())
+		assert.Equal(t, 2, manager.store.Len())
+
+		current, transacted := manager.store.Transaction(innerCtx)
+		assert.True(t, transacted)
+		assert.Same(t, inner, current)
+
+		innerCtx, err = inner.Commit(innerCtx)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, manager.store.Len())
+
+		// once the inner transaction is done, the outer one is current again.
+		current, transacted = manager.store.Transaction(innerCtx)
+		assert.True(t, transacted)
+		assert.Equal(t, "1", current.ID())
+
+		return someErr
+	})
+	assert.ErrorIs(t, err, someErr)
+	assert.Equal(t, 0, manager.store.Len())
+}
+
+func TestWithoutTransaction(t *testing.T) {
+	db := txtest.NewDB(t)
+	manager := &Manager{
+		db:    db,
+		store: newStore(),
+	}
+
+	type ctxKey struct{}
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "value"))
+	defer cancel()
+	txContext := txtest.WithContext(ctx)
+
+	outerTx := txtest.NewTx(t)
+	innerTx := txtest.NewTx(t)
+	db.On("Begin", txContext, nilTxOptions).Return(outerTx, nil)
+	db.On("Begin", mock.Anything, nilTxOptions).Return(innerTx, nil).Once()
+	innerTx.On("Commit", mock.Anything).Return(nil)
+	outerTx.On("Rollback", txtest.MatchContext(txContext)).Return(nil)
+
+	someErr := errors.New("some error")
+
+	err := manager.BeginFunc(ctx, func(ctx context.Context) error {
+		detached := WithoutTransaction(ctx)
+		assert.Equal(t, "value", detached.Value(ctxKey{}))
+
+		_, transacted := TransactionID(detached)
+		assert.False(t, transacted)
+		_, transacted = manager.store.Transaction(detached)
+		assert.False(t, transacted)
+
+		// a transaction begun with the detached context is a new root transaction.
+		err := manager.BeginFunc(detached, func(ctx context.Context) error {
+			id, _ := TransactionID(ctx)
+			assert.Equal(t, "2", id)
+			return nil
+		})
+		assert.NoError(t, err)
+
+		// the transaction of the original context is still current.
+		id, _ := TransactionID(ctx)
+		assert.Equal(t, "1", id)
+
+		return someErr
+	})
+	assert.ErrorIs(t, err, someErr)
+	assert.Equal(t, 0, manager.store.Len())
+
+	// the context without a transaction is returned as is.
+	assert.Equal(t, ctx, WithoutTransaction(ctx))
+}
+
+func TestBeginRemovesCompletedTransactionFromStore(t *testing.T) {
+	db := txtest.NewDB(t)
+	manager := &Manager{
+		db:    db,
+		store: newStore(),
+	}
+
+	ctx := context.Background()
+	txContext := txtest.WithContext(ctx)
+
+	tx := txtest.NewTx(t)
+	db.On("Begin", txContext, nilTxOptions).Return(tx, nil)
+	tx.On("Commit", txtest.MatchContext(txContext)).Return(nil)
+
+	ctx, transaction, err := manager.Begin(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, manager.store.Len())
+
+	_, err = transaction.Commit(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, manager.store.Len())
+}
+
+func TestBeginFuncPanicRollsBackAndRepanics(t *testing.T) {
+	db := txtest.NewDB(t)
+	manager := &Manager{
+		db:    db,
+		store: newStore(),
+	}
+
+	baseContext := context.Background()
+	txContext := txtest.WithContext(baseContext)
+
+	tx := txtest.NewTx(t)
+	tx.On("Rollback", txtest.MatchContext(txContext)).Return(nil)
+	db.On("Begin", txContext, nilTxOptions).Return(tx, nil)
+
+	assert.PanicsWithValue(t, "boom", func() {
+		_ = manager.BeginFunc(baseContext, func(_ context.Context) error {
+			panic("boom")
+		})
+	})
+	assert.Equal(t, 0, manager.store.Len())
+}
+
+func TestBeginFuncPanicAsError(t *testing.T) {
+	db := txtest.NewDB(t)
+	manager := &Manager{
+		db:    db,
+		store: newStore(),
+	}
+
+	baseContext := context.Background()
+	txContext := txtest.WithContext(baseContext)
+
+	panicErr := errors.New("panic error")
+
+	tx := txtest.NewTx(t)
+	tx.On("Rollback", txtest.MatchContext(txContext)).Return(nil)
+	db.On("Begin", txContext, &txsql.TxOptions{PanicAsError: true}).Return(tx, nil)
+
+	err := manager.BeginFunc(baseContext, func(_ context.Context) error {
+		panic(panicErr)
+	}, txsql.WithPanicAsError())
+
+	var pe *PanicError
+	assert.ErrorAs(t, err, &pe)
+	assert.Equal(t, panicErr, pe.Value)
+	assert.NotEmpty(t, pe.Stack)
+	assert.ErrorIs(t, err, panicErr)
+	assert.Equal(t, 0, manager.store.Len())
+}
+
+func TestBeginFuncChildPanicMarksParentForRollback(t *testing.T) {
+	db := txtest.NewDB(t)
+	manager := &Manager{
+		db:    db,
+		store: newStore(),
+	}
+
+	baseContext := context.Background()
+	txContext := txtest.WithContext(baseContext)
+
+	tx := txtest.NewTx(t)
+	tx.On("Rollback", txtest.MatchContext(txContext)).Return(nil)
+	db.On("Begin", txContext, nilTxOptions).Return(tx, nil)
+
+	err := manager.BeginFunc(baseContext, func(ctx context.Context) error {
+		err := manager.BeginFunc(ctx, func(_ context.Context) error {
+			panic("boom")
+		}, txsql.WithPanicAsError())
+
+		var pe *PanicError
+		assert.ErrorAs(t, err, &pe)
+
+		// the parent ignores the error, but the transaction cannot be committed anymore.
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrRollbackOnly)
+	assert.Equal(t, 0, manager.store.Len())
+}
+
+func TestDo(t *testing.T) {
+	db := txtest.NewDB(t)
+	manager := &Manager{
+		db:    db,
+		store: newStore(),
+	}
+
+	baseContext := context.Background()
+	txContext := txtest.WithContext(baseContext)
+
+	tx := txtest.NewTx(t)
+	tx.On("Commit", txtest.MatchContext(txContext)).Return(nil)
+	db.On("Begin", txContext, nilTxOptions).Return(tx, nil)
+
+	v, err := Do(baseContext, manager, func(_ context.Context) (int, error) { return 42, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, 42, v)
+	assert.Equal(t, 0, manager.store.Len())
+}
+
+func TestDoCommitFails(t *testing.T) {
+	db := txtest.NewDB(t)
+	manager := &Manager{
+		db:    db,
+		store: newStore(),
+	}
+
+	baseContext := context.Background()
+	txContext := txtest.WithContext(baseContext)
+
+	someErr := errors.New("some error")
+
+	tx := txtest.NewTx(t)
+	tx.On("Commit", txtest.MatchContext(txContext)).Return(someErr)
+	db.On("Begin", txContext, nilTxOptions).Return(tx, nil)
+
+	// the value built within the failed transaction must not leak out.
+	v, err := Do(baseContext, manager, func(_ context.Context) (*int, error) {
+		v := 42
+		return &v, nil
+	})
+	assert.ErrorIs(t, err, someErr)
+	assert.Nil(t, v)
+	assert.Equal(t, 0, manager.store.Len())
+}
+
+func TestBeginTimeoutRollsBackTransaction(t *testing.T) {
+	db := txtest.NewDB(t)
+	manager := &Manager{
+		db:    db,
+		store: newStore(),
+	}
+
+	rolledBack := make(chan struct{})
+
+	tx := txtest.NewTx(t)
+	db.On("Begin", mock.Anything, mock.Anything).Return(tx, nil)
+	tx.On("Rollback", mock.Anything).Return(nil).Once().Run(func(_ mock.Arguments) { close(rolledBack) })
+
+	ctx, transaction, err := manager.Begin(context.Background(), txsql.WithTimeout(10*time.Millisecond))
+	assert.NoError(t, err)
+
+	deadline, ok := ctx.Deadline()
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(10*time.Millisecond), deadline, 10*time.Millisecond)
+
+	select {
+	case <-rolledBack:
+	case <-time.After(time.Second):
+		t.Fatal("transaction has not been rolled back after the timeout")
+	}
+
+	_, err = transaction.Commit(ctx)
+	assert.ErrorIs(t, err, ErrTransactionTimeout)
+	assert.Equal(t, 0, manager.store.Len())
+}
+
+func TestBeginFuncTimeout(t *testing.T) {
+	db := txtest.NewDB(t)
+	manager := &Manager{
+		db:    db,
+		store: newStore(),
+	}
+
+	tx := txtest.NewTx(t)
+	db.On("Begin", mock.Anything, mock.Anything).Return(tx, nil)
+	tx.On("Rollback", mock.Anything).Return(nil).Once()
+
+	err := manager.BeginFunc(context.Background(), func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, txsql.WithTimeout(10*time.Millisecond))
+	assert.ErrorIs(t, err, ErrTransactionTimeout)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 0, manager.store.Len())
+}
+
+func TestBeginFuncTimeoutWaitsForRollback(t *testing.T) {
+	db := txtest.NewDB(t)
+	manager := &Manager{
+		db:    db,
+		store: newStore(),
+	}
+
+	var rolledBack atomic.Bool
+	tx := txtest.NewTx(t)
+	db.On("Begin", mock.Anything, mock.Anything).Return(tx, nil)
+	tx.On("Rollback", mock.Anything).Run(func(mock.Arguments) {
+		time.Sleep(20 * time.Millisecond)
+		rolledBack.Store(true)
+	}).Return(nil).Once()
+
+	err := manager.BeginFunc(context.Background(), func(ctx context.Context) error {
+		assert.NoError(t, manager.AfterRollback(ctx, func(_ context.Context) {
+			assert.True(t, rolledBack.Load(), "Expected the hook to run after the rollback")
+		}))
+
+		<-ctx.Done()
+		return ctx.Err()
+	}, txsql.WithTimeout(10*time.Millisecond))
+	assert.ErrorIs(t, err, ErrTransactionTimeout)
+
+	// the transaction completes only once the rollback started by the timeout has returned.
+	assert.True(t, rolledBack.Load())
+	assert.Equal(t, 0, manager.store.Len())
+}
+
+func TestBeginFuncCompletesBeforeTimeout(t *testing.T) {
+	db := txtest.NewDB(t)
+	manager := &Manager{
+		db:    db,
+		store: newStore(),
+	}
+
+	tx := txtest.NewTx(t)
+	db.On("Begin", mock.Anything, mock.Anything).Return(tx, nil)
+	tx.On("Commit", mock.Anything).Return(nil)
+
+	var txCtx context.Context
+	err := manager.BeginFunc(context.Background(), func(ctx context.Context) error {
+		txCtx = ctx
+		return nil
+	}, txsql.WithTimeout(time.Hour))
+	assert.NoError(t, err)
+
+	// the timeout is released once the transaction is completed.
+	<-txCtx.Done()
+	assert.ErrorIs(t, txCtx.Err(), context.Canceled)
+	tx.AssertNotCalled(t, "Rollback", mock.Anything)
+}
+
+func TestBeginFuncRollsBackOnCancel(t *testing.T) {
+	tests := []struct {
+		name  string
+		fnErr func(ctx context.Context) error
+	}{
+		{
+			name:  "function succeeds",
+			fnErr: func(_ context.Context) error { return nil },
+		},
+		{
+			name:  "function fails",
+			fnErr: func(_ context.Context) error { return errors.New("some error") },
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			db := txtest.NewDB(t)
+			manager := &Manager{
+				db:    db,
+				store: newStore(),
+			}
+
+			rolledBack := make(chan struct{})
+			tx := txtest.NewTx(t)
+			db.On("Begin", mock.Anything, nilTxOptions).Return(tx, nil)
+			tx.On("Rollback", mock.Anything).Run(func(mock.Arguments) { close(rolledBack) }).Return(nil).Once()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			err := manager.BeginFunc(ctx, func(ctx context.Context) error {
+				cancel()
+
+				// the transaction is rolled back without waiting for the function to return.
+				select {
+				case <-rolledBack:
+				case <-time.After(time.Second):
+					t.Error("transaction has not been rolled back on cancellation")
+				}
+				return tt.fnErr(ctx)
+			})
+			assert.ErrorIs(t, err, context.Canceled)
+			assert.Equal(t, 0, manager.store.Len())
+		})
+	}
+}
+
+func TestBeginFuncRollbackOnCancelKeepsValues(t *testing.T) {
+	type ctxKey struct{}
+
+	db := txtest.NewDB(t)
+
+	rolledBack := make(chan struct{})
+	var interceptorValue, hookValue any
+	interceptor := func(ctx context.Context, info InterceptorInfo, next Handler) error {
+		if info.Operation == OpRollback {
+			interceptorValue = ctx.Value(ctxKey{})
+		}
+		return next(ctx)
+	}
+	manager, _, err := NewManager(func(_ TransactionStore) (txsql.DB, error) { return db, nil },
+		WithInterceptors(interceptor))
+	require.NoError(t, err)
+
+	tx := txtest.NewTx(t)
+	db.On("Begin", mock.Anything, nilTxOptions).Return(tx, nil)
+	tx.On("Rollback", mock.Anything).Return(nil).Once()
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "value"))
+	defer cancel()
+
+	err = manager.BeginFunc(ctx, func(ctx context.Context) error {
+		require.NoError(t, manager.AfterRollback(ctx, func(ctx context.Context) {
+			hookValue = ctx.Value(ctxKey{})
+			close(rolledBack)
+		}))
+		cancel()
+
+		select {
+		case <-rolledBack:
+		case <-time.After(time.Second):
+			t.Error("transaction has not been rolled back on cancellation")
+		}
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// the rollback on cancellation sees the values of the context of the caller.
+	assert.Equal(t, "value", interceptorValue)
+	assert.Equal(t, "value", hookValue)
+}
+
+func TestBeginFuncCanceledBeforeCommit(t *testing.T) {
+	db := txtest.NewDB(t)
+	manager := &Manager{
+		db:    db,
+		store: newStore(),
+	}
+
+	tx := txtest.NewTx(t)
+	db.On("Begin", mock.Anything, nilTxOptions).Return(tx, nil)
+	// the commit races with the rollback started by the cancellation.
+	tx.On("Commit", mock.Anything).Return(sql.ErrTxDone).Maybe()
+	tx.On("Rollback", mock.Anything).Return(nil).Maybe()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err := manager.BeginFunc(ctx, func(_ context.Context) error {
+		cancel()
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	var txErr *TxError
+	if assert.ErrorAs(t, err, &txErr) {
+		assert.Equal(t, OpCommit, txErr.Op)
+	}
+}
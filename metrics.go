@@ -0,0 +1,36 @@
+package transact
+
+import "time"
+
+// Outcome is the way a transaction has completed.
+type Outcome string
+
+// Outcomes reported to Metrics.
+const (
+	// OutcomeCommitted is a transaction that has been committed.
+	OutcomeCommitted Outcome = "committed"
+	// OutcomeRolledBack is a transaction that has been rolled back.
+	OutcomeRolledBack Outcome = "rolled_back"
+	// OutcomeCommitFailed is a transaction that has failed to commit.
+	OutcomeCommitFailed Outcome = "commit_failed"
+)
+
+// Metrics collects metrics of the transactions started by a manager.
+// Only root transactions are reported, nested scopes are a part of the transaction they join.
+// The methods are called concurrently and must not block.
+type Metrics interface {
+	// TransactionBegun is called when a transaction has begun.
+	TransactionBegun()
+
+	// TransactionCompleted is called once for every begun transaction when it has completed.
+	// The duration is the time since the transaction has begun, and statements is
+	// the number of statements executed within the transaction.
+	TransactionCompleted(outcome Outcome, duration time.Duration, statements int)
+}
+
+// WithMetrics makes the manager report the transactions to the metrics.
+func WithMetrics(metrics Metrics) Option {
+	return func(m *Manager) {
+		m.metrics = metrics
+	}
+}
@@ -0,0 +1,97 @@
+package transact
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sklyar/go-transact/txtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type completion struct {
+	outcome    Outcome
+	statements int
+}
+
+type recordingMetrics struct {
+	begun     int
+	completed []completion
+}
+
+func (m *recordingMetrics) TransactionBegun() {
+	m.begun++
+}
+
+func (m *recordingMetrics) TransactionCompleted(outcome Outcome, _ time.Duration, statements int) {
+	m.completed = append(m.completed, completion{outcome: outcome, statements: statements})
+}
+
+func TestWithMetrics(t *testing.T) {
+	someErr := errors.New("some error")
+
+	tests := []struct {
+		name  string
+		setup func(tx *txtest.Tx)
+		fnErr error
+		want  completion
+	}{
+		{
+			name: "committed",
+			setup: func(tx *txtest.Tx) {
+				tx.On("Commit", mock.Anything).Return(nil)
+			},
+			want: completion{outcome: OutcomeCommitted, statements: 2},
+		},
+		{
+			name: "rolled back",
+			setup: func(tx *txtest.Tx) {
+				tx.On("Rollback", mock.Anything).Return(nil)
+			},
+			fnErr: someErr,
+			want:  completion{outcome: OutcomeRolledBack, statements: 2},
+		},
+		{
+			name: "commit failed",
+			setup: func(tx *txtest.Tx) {
+				tx.On("Commit", mock.Anything).Return(someErr)
+			},
+			want: completion{outcome: OutcomeCommitFailed, statements: 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics := new(recordingMetrics)
+
+			db := txtest.NewDB(t)
+			manager := &Manager{
+				db:      db,
+				store:   newStore(),
+				metrics: metrics,
+			}
+
+			tx := txtest.NewTx(t)
+			db.On("Begin", mock.Anything, nilTxOptions).Return(tx, nil)
+			tx.On("Exec", mock.Anything, "UPDATE users SET name = $1", "alice").Return(nil, nil)
+			tt.setup(tx)
+
+			_ = manager.BeginFunc(context.Background(), func(ctx context.Context) error {
+				// the nested scope is a part of the root transaction.
+				return manager.BeginFunc(ctx, func(ctx context.Context) error {
+					transaction, _ := manager.store.Transaction(ctx)
+					for i := 0; i < 2; i++ {
+						if _, err := transaction.Exec(ctx, "UPDATE users SET name = $1", "alice"); err != nil {
+							return err
+						}
+					}
+					return tt.fnErr
+				})
+			})
+
+			assert.Equal(t, 1, metrics.begun)
+			assert.Equal(t, []completion{tt.want}, metrics.completed)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package transact
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sklyar/go-transact/txsql"
+)
+
+// ErrIncompatibleTransaction is returned when a transaction joining an existing one
+// requests options that the existing transaction cannot provide.
+var ErrIncompatibleTransaction = errors.New("incompatible nested transaction")
+
+// WithLenientNesting makes the manager report options conflicts of nested transactions
+// to the report function instead of failing. The nested transaction then joins
+// the existing one with the options of the latter, as if no options were requested.
+func WithLenientNesting(report func(ctx context.Context, err error)) Option {
+	return func(m *Manager) {
+		m.reportNestingConflict = report
+	}
+}
+
+// checkNesting checks that the options of a transaction joining an existing one
+// are compatible with the options the existing transaction has been started with.
+//
+// Only the options the nested transaction explicitly requests are checked.
+// It may not request a stricter standard isolation level than the existing transaction has been started with,
+// and it may not request to be writable with txsql.WithReadWrite if the existing transaction is read-only.
+func (m *Manager) checkNesting(ctx context.Context, parent, child *txsql.TxOptions) error {
+	if child == nil {
+		return nil
+	}
+	if parent == nil {
+		parent = new(txsql.TxOptions)
+	}
+
+	childRank, childRanked := isolationRank(child.Isolation)
+	parentRank, parentRanked := isolationRank(parent.Isolation)
+
+	var err error
+	switch {
+	case childRanked && parentRanked && childRank > parentRank:
+		err = fmt.Errorf("%w: isolation level %s is requested, but the parent transaction uses %s",
+			ErrIncompatibleTransaction, child.Isolation, parent.Isolation)
+	case parent.ReadOnly && child.ReadWrite:
+		err = fmt.Errorf("%w: writable transaction is requested, but the parent transaction is read-only",
+			ErrIncompatibleTransaction)
+	default:
+		return nil
+	}
+
+	if m.reportNestingConflict != nil {
+		m.reportNestingConflict(ctx, err)
+		return nil
+	}
+	return err
+}
+
+// isolationRank returns the strictness of the standard SQL isolation level.
+// The default level depends on the database, and the other levels are not comparable
+// with the standard ones across databases, so they are not ranked.
+func isolationRank(level txsql.IsolationLevel) (int, bool) {
+	switch level {
+	case txsql.LevelReadUncommitted:
+		return 1, true
+	case txsql.LevelReadCommitted:
+		return 2, true
+	case txsql.LevelRepeatableRead:
+		return 3, true
+	case txsql.LevelSerializable:
+		return 4, true
+	default:
+		return 0, false
+	}
+}
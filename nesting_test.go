@@ -0,0 +1,147 @@
+package transact
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sklyar/go-transact/txsql"
+	"github.com/sklyar/go-transact/txtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestBeginNestedOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		parent  []txsql.TransactionOption
+		child   []txsql.TransactionOption
+		wantErr string
+	}{
+		{
+			name:   "child without options",
+			parent: []txsql.TransactionOption{txsql.WithReadOnly()},
+		},
+		{
+			name:   "same isolation level",
+			parent: []txsql.TransactionOption{txsql.WithIsolationLevel(txsql.LevelSerializable)},
+			child:  []txsql.TransactionOption{txsql.WithIsolationLevel(txsql.LevelSerializable)},
+		},
+		{
+			name:   "weaker isolation level",
+			parent: []txsql.TransactionOption{txsql.WithIsolationLevel(txsql.LevelSerializable)},
+			child:  []txsql.TransactionOption{txsql.WithIsolationLevel(txsql.LevelReadCommitted)},
+		},
+		{
+			name:    "stricter isolation level",
+			parent:  []txsql.TransactionOption{txsql.WithIsolationLevel(txsql.LevelReadCommitted)},
+			child:   []txsql.TransactionOption{txsql.WithIsolationLevel(txsql.LevelSerializable)},
+			wantErr: "incompatible nested transaction: isolation level Serializable is requested, but the parent transaction uses Read Committed",
+		},
+		{
+			// the non-standard levels are not comparable with the standard ones.
+			name:   "snapshot within repeatable read parent",
+			parent: []txsql.TransactionOption{txsql.WithIsolationLevel(txsql.LevelRepeatableRead)},
+			child:  []txsql.TransactionOption{txsql.WithIsolationLevel(txsql.LevelSnapshot)},
+		},
+		{
+			name:   "write committed within read committed parent",
+			parent: []txsql.TransactionOption{txsql.WithIsolationLevel(txsql.LevelReadCommitted)},
+			child:  []txsql.TransactionOption{txsql.WithIsolationLevel(txsql.LevelWriteCommitted)},
+		},
+		{
+			name:    "serializable within repeatable read parent",
+			parent:  []txsql.TransactionOption{txsql.WithIsolationLevel(txsql.LevelRepeatableRead)},
+			child:   []txsql.TransactionOption{txsql.WithIsolationLevel(txsql.LevelSerializable)},
+			wantErr: "incompatible nested transaction: isolation level Serializable is requested, but the parent transaction uses Repeatable Read",
+		},
+		{
+			// the default isolation level of the parent is unknown.
+			name:  "isolation level within default parent",
+			child: []txsql.TransactionOption{txsql.WithIsolationLevel(txsql.LevelRepeatableRead)},
+		},
+		{
+			name:   "weaker isolation level within default parent",
+			parent: []txsql.TransactionOption{txsql.WithReadOnly()},
+			child:  []txsql.TransactionOption{txsql.WithIsolationLevel(txsql.LevelReadUncommitted)},
+		},
+		{
+			name:   "read-only child within writable parent",
+			child:  []txsql.TransactionOption{txsql.WithReadOnly()},
+			parent: []txsql.TransactionOption{txsql.WithIsolationLevel(txsql.LevelReadCommitted)},
+		},
+		{
+			name:   "mandatory child within read-only parent",
+			parent: []txsql.TransactionOption{txsql.WithReadOnly()},
+			child:  []txsql.TransactionOption{txsql.WithPropagation(txsql.PropagationMandatory)},
+		},
+		{
+			name:   "nested child within read-only parent",
+			parent: []txsql.TransactionOption{txsql.WithReadOnly()},
+			child:  []txsql.TransactionOption{txsql.WithNested()},
+		},
+		{
+			name:   "child with unrelated options within read-only parent",
+			parent: []txsql.TransactionOption{txsql.WithReadOnly()},
+			child:  []txsql.TransactionOption{txsql.WithPanicAsError(), txsql.WithTimeout(time.Minute)},
+		},
+		{
+			name:    "writable child within read-only parent",
+			parent:  []txsql.TransactionOption{txsql.WithReadOnly()},
+			child:   []txsql.TransactionOption{txsql.WithReadWrite()},
+			wantErr: "incompatible nested transaction: writable transaction is requested, but the parent transaction is read-only",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := txtest.NewDB(t)
+			manager := &Manager{
+				db:    db,
+				store: newStore(),
+			}
+
+			tx := txtest.NewTx(t)
+			db.On("Begin", mock.Anything, mock.Anything).Return(tx, nil)
+			tx.On("Savepoint", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+			ctx, _, err := manager.Begin(context.Background(), tt.parent...)
+			assert.NoError(t, err)
+
+			_, _, err = manager.Begin(ctx, tt.child...)
+			if tt.wantErr != "" {
+				assert.ErrorIs(t, err, ErrIncompatibleTransaction)
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestBeginNestedOptionsLenient(t *testing.T) {
+	db := txtest.NewDB(t)
+
+	var reported []error
+	manager := &Manager{
+		db:    db,
+		store: newStore(),
+	}
+	WithLenientNesting(func(_ context.Context, err error) {
+		reported = append(reported, err)
+	})(manager)
+
+	tx := txtest.NewTx(t)
+	db.On("Begin", mock.Anything, mock.Anything).Return(tx, nil)
+
+	ctx, parent, err := manager.Begin(context.Background(),
+		txsql.WithIsolationLevel(txsql.LevelReadCommitted), txsql.WithReadOnly())
+	assert.NoError(t, err)
+
+	_, child, err := manager.Begin(ctx, txsql.WithIsolationLevel(txsql.LevelSerializable), txsql.WithReadOnly())
+	assert.NoError(t, err)
+	assert.Same(t, parent, child)
+
+	if assert.Len(t, reported, 1) {
+		assert.ErrorIs(t, reported[0], ErrIncompatibleTransaction)
+	}
+}
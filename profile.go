@@ -0,0 +1,50 @@
+package transact
+
+import (
+	"context"
+	"runtime/pprof"
+
+	"github.com/sklyar/go-transact/txsql"
+)
+
+// Keys of the pprof labels set by WithProfilerLabels.
+const (
+	LabelTransactionName      = "tx_name"
+	LabelTransactionIsolation = "tx_isolation"
+)
+
+// WithProfilerLabels makes BeginFunc run the transaction function with pprof labels
+// describing its transaction: the name given with txsql.WithName, if any, and the isolation level.
+// CPU and goroutine profiles can then be broken down by transaction.
+// The labels are carried by the context, so nested scopes keep the labels of their transaction.
+func WithProfilerLabels() Option {
+	return func(m *Manager) {
+		m.profilerLabels = true
+	}
+}
+
+// run executes the transaction function within the scope of the transaction.
+func (m *Manager) run(ctx context.Context, tx *Transaction, fn TransactionFunc) error {
+	if !m.profilerLabels || tx.empty {
+		return fn(ctx)
+	}
+
+	var err error
+	pprof.Do(ctx, profilerLabels(tx.options), func(ctx context.Context) {
+		err = fn(ctx)
+	})
+	return err
+}
+
+// profilerLabels returns the pprof labels of a transaction started with the options.
+func profilerLabels(txOptions *txsql.TxOptions) pprof.LabelSet {
+	if txOptions == nil {
+		txOptions = new(txsql.TxOptions)
+	}
+
+	labels := []string{LabelTransactionIsolation, txOptions.Isolation.String()}
+	if txOptions.Name != "" {
+		labels = append(labels, LabelTransactionName, txOptions.Name)
+	}
+	return pprof.Labels(labels...)
+}
@@ -0,0 +1,46 @@
+package transact
+
+import (
+	"context"
+	"runtime/pprof"
+	"testing"
+
+	"github.com/sklyar/go-transact/txsql"
+	"github.com/sklyar/go-transact/txtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestWithProfilerLabels(t *testing.T) {
+	db := txtest.NewDB(t)
+	manager := &Manager{
+		db:    db,
+		store: newStore(),
+	}
+	WithProfilerLabels()(manager)
+
+	tx := txtest.NewTx(t)
+	db.On("Begin", mock.Anything, mock.Anything).Return(tx, nil)
+	tx.On("Commit", mock.Anything).Return(nil)
+
+	labels := func(ctx context.Context) map[string]string {
+		got := make(map[string]string)
+		pprof.ForLabels(ctx, func(key, value string) bool {
+			got[key] = value
+			return true
+		})
+		return got
+	}
+
+	err := manager.BeginFunc(context.Background(), func(ctx context.Context) error {
+		want := map[string]string{"tx_name": "create_order", "tx_isolation": "Serializable"}
+		assert.Equal(t, want, labels(ctx))
+
+		// nested scopes keep the labels of their transaction.
+		return manager.BeginFunc(ctx, func(ctx context.Context) error {
+			assert.Equal(t, want, labels(ctx))
+			return nil
+		})
+	}, txsql.WithName("create_order"), txsql.WithIsolationLevel(txsql.LevelSerializable))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,132 @@
+package transact
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/rand"
+	"syscall"
+	"time"
+
+	"github.com/sklyar/go-transact/txsql"
+)
+
+const (
+	defaultMaxAttempts    = 3
+	defaultInitialBackoff = 10 * time.Millisecond
+	defaultMultiplier     = 2
+)
+
+// SQLSTATE codes of errors after which a transaction can be safely retried.
+const (
+	sqlStateSerializationFailure = "40001"
+	sqlStateDeadlockDetected     = "40P01"
+)
+
+// RetryError is returned by BeginFunc with a retry policy if the transaction
+// has not succeeded. It carries the number of attempts made.
+type RetryError struct {
+	// Attempts is the number of times the transaction function has been executed.
+	Attempts int
+	// Err is the error of the last attempt.
+	Err error
+}
+
+// Error implements error interface.
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("transaction failed after %d attempt(s): %v", e.Attempts, e.Err)
+}
+
+// Unwrap returns the error of the last attempt.
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// IsRetryable reports whether a transaction that failed with the error can be retried.
+// Serialization failures (SQLSTATE 40001) and deadlocks (SQLSTATE 40P01) are retryable.
+// Broken connections are retryable only if they occurred before the commit,
+// since the outcome of an interrupted commit is unknown.
+//
+// The SQLSTATE is taken from errors implementing the SQLState() string method,
+// such as the ones of pgx and lib/pq drivers.
+func IsRetryable(err error) bool {
+	var sqlErr interface{ SQLState() string }
+	if errors.As(err, &sqlErr) {
+		switch sqlErr.SQLState() {
+		case sqlStateSerializationFailure, sqlStateDeadlockDetected:
+			return true
+		}
+	}
+
+	var txErr *TxError
+	for cause := err; errors.As(cause, &txErr); cause = txErr.Err {
+		if txErr.Op == OpCommit {
+			return false
+		}
+	}
+
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// beginFuncWithRetry executes the transaction function in a new transaction
+// until it succeeds, fails with a non-retryable error or runs out of attempts.
+func (m *Manager) beginFuncWithRetry(ctx context.Context, fn TransactionFunc, txOptions *txsql.TxOptions) error {
+	policy := txOptions.Retry
+
+	maxAttempts := policy.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := m.beginFunc(ctx, fn, txOptions)
+		if err == nil {
+			return nil
+		}
+		if attempt >= maxAttempts || !retryable(err) {
+			return &RetryError{Attempts: attempt, Err: err}
+		}
+
+		timer := time.NewTimer(backoff(policy, attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return &RetryError{Attempts: attempt, Err: errors.Join(err, ctx.Err())}
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns the delay before the next attempt after the given number of attempts.
+func backoff(policy *txsql.RetryPolicy, attempt int) time.Duration {
+	delay := policy.InitialBackoff
+	if delay <= 0 {
+		delay = defaultInitialBackoff
+	}
+	multiplier := policy.Multiplier
+	if multiplier < 1 {
+		multiplier = defaultMultiplier
+	}
+
+	d := float64(delay)
+	for i := 1; i < attempt; i++ {
+		d *= multiplier
+		if policy.MaxBackoff > 0 && d >= float64(policy.MaxBackoff) {
+			break
+		}
+	}
+	if policy.MaxBackoff > 0 && d > float64(policy.MaxBackoff) {
+		d = float64(policy.MaxBackoff)
+	}
+
+	if jitter := min(max(policy.Jitter, 0), 1); jitter > 0 {
+		d -= d * jitter * rand.Float64() //nolint:gosec // jitter doesn't need a secure random.
+	}
+
+	return time.Duration(d)
+}
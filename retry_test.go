@@ -0,0 +1,178 @@
+package transact
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sklyar/go-transact/txsql"
+	"github.com/sklyar/go-transact/txtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type sqlStateError string
+
+func (e sqlStateError) Error() string    { return "sqlstate " + string(e) }
+func (e sqlStateError) SQLState() string { return string(e) }
+
+func TestIsRetryable(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "serialization failure", err: sqlStateError("40001"), want: true},
+		{name: "deadlock detected", err: fmt.Errorf("wrapped: %w", sqlStateError("40P01")), want: true},
+		{name: "serialization failure on commit", err: &TxError{Op: OpCommit, Err: sqlStateError("40001")}, want: true},
+		{name: "unique violation", err: sqlStateError("23505"), want: false},
+		{name: "bad connection", err: driver.ErrBadConn, want: true},
+		{name: "bad connection on commit", err: &TxError{Op: OpCommit, Err: driver.ErrBadConn}, want: false},
+		{name: "bad connection in function", err: &TxError{Op: OpExec, Err: driver.ErrBadConn}, want: true},
+		{
+			name: "bad connection on commit of inner transaction",
+			err:  &TxError{Op: OpExec, Err: &TxError{Op: OpCommit, Err: driver.ErrBadConn}},
+			want: false,
+		},
+		{name: "other error", err: errors.New("some error"), want: false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, IsRetryable(tt.err))
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	t.Parallel()
+
+	policy := &txsql.RetryPolicy{
+		InitialBackoff: 10 * time.Millisecond,
+		MaxBackoff:     30 * time.Millisecond,
+	}
+	assert.Equal(t, 10*time.Millisecond, backoff(policy, 1))
+	assert.Equal(t, 20*time.Millisecond, backoff(policy, 2))
+	assert.Equal(t, 30*time.Millisecond, backoff(policy, 3))
+	assert.Equal(t, 30*time.Millisecond, backoff(policy, 100))
+
+	policy.Jitter = 0.5
+	for i := 0; i < 10; i++ {
+		d := backoff(policy, 1)
+		assert.GreaterOrEqual(t, d, 5*time.Millisecond)
+		assert.LessOrEqual(t, d, 10*time.Millisecond)
+	}
+}
+
+func TestBeginFuncRetriesSerializationFailure(t *testing.T) {
+	db := txtest.NewDB(t)
+	manager := &Manager{
+		db:    db,
+		store: newStore(),
+	}
+
+	serializationErr := sqlStateError("40001")
+
+	failedTx := txtest.NewTx(t)
+	failedTx.On("Commit", mock.Anything).Return(serializationErr)
+	tx := txtest.NewTx(t)
+	tx.On("Commit", mock.Anything).Return(nil)
+	db.On("Begin", mock.Anything, mock.Anything).Return(failedTx, nil).Once()
+	db.On("Begin", mock.Anything, mock.Anything).Return(tx, nil).Once()
+
+	var calls int
+	err := manager.BeginFunc(context.Background(), func(_ context.Context) error {
+		calls++
+		return nil
+	}, txsql.WithRetry(txsql.RetryPolicy{InitialBackoff: time.Millisecond}))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, 0, manager.store.Len())
+}
+
+func TestBeginFuncRetriesExhausted(t *testing.T) {
+	db := txtest.NewDB(t)
+	manager := &Manager{
+		db:    db,
+		store: newStore(),
+	}
+
+	deadlockErr := sqlStateError("40P01")
+
+	tx := txtest.NewTx(t)
+	tx.On("Rollback", mock.Anything).Return(nil)
+	db.On("Begin", mock.Anything, mock.Anything).Return(tx, nil)
+
+	err := manager.BeginFunc(context.Background(), func(_ context.Context) error {
+		return deadlockErr
+	}, txsql.WithRetry(txsql.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}))
+
+	var retryErr *RetryError
+	assert.ErrorAs(t, err, &retryErr)
+	assert.Equal(t, 2, retryErr.Attempts)
+	assert.ErrorIs(t, err, deadlockErr)
+	db.AssertNumberOfCalls(t, "Begin", 2)
+}
+
+func TestBeginFuncDoesNotRetryNonRetryableError(t *testing.T) {
+	db := txtest.NewDB(t)
+	manager := &Manager{
+		db:    db,
+		store: newStore(),
+	}
+
+	someErr := errors.New("some error")
+
+	tx := txtest.NewTx(t)
+	tx.On("Rollback", mock.Anything).Return(nil)
+	db.On("Begin", mock.Anything, mock.Anything).Return(tx, nil).Once()
+
+	err := manager.BeginFunc(context.Background(), func(_ context.Context) error {
+		return someErr
+	}, txsql.WithRetry(txsql.RetryPolicy{}))
+
+	var retryErr *RetryError
+	assert.ErrorAs(t, err, &retryErr)
+	assert.Equal(t, 1, retryErr.Attempts)
+	assert.ErrorIs(t, err, someErr)
+}
+
+func TestBeginFuncDoesNotRetryChildTransaction(t *testing.T) {
+	db := txtest.NewDB(t)
+	manager := &Manager{
+		db:    db,
+		store: newStore(),
+	}
+
+	ctx := context.Background()
+	txContext := txtest.WithContext(ctx)
+	childTxContext := txtest.WithChildContext(txContext)
+
+	serializationErr := sqlStateError("40001")
+
+	tx := txtest.NewTx(t)
+	db.On("Begin", txContext, nilTxOptions).Return(tx, nil)
+	tx.On("Rollback", txtest.MatchContext(childTxContext)).Return(nil)
+
+	var calls int
+	err := manager.BeginFunc(ctx, func(ctx context.Context) error {
+		return manager.BeginFunc(ctx, func(_ context.Context) error {
+			calls++
+			return serializationErr
+		}, txsql.WithRetry(txsql.RetryPolicy{InitialBackoff: time.Millisecond}))
+	})
+	assert.ErrorIs(t, err, serializationErr)
+	assert.Equal(t, 1, calls)
+
+	var retryErr *RetryError
+	assert.False(t, errors.As(err, &retryErr), "Expected child transaction not to be retried")
+}
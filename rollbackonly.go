@@ -0,0 +1,46 @@
+package transact
+
+import "context"
+
+// SetRollbackOnly marks the transaction as rollback-only.
+// The root scope then rolls the transaction back instead of committing it,
+// and the commit fails with ErrRollbackOnly.
+// It does nothing if the transaction represents a non-transactional scope.
+func (tx *Transaction) SetRollbackOnly() {
+	if tx.empty {
+		return
+	}
+
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	tx.rollbackOnly = true
+}
+
+// IsRollbackOnly reports whether the transaction can no longer be committed,
+// because it has been marked as rollback-only or has already been rolled back.
+func (tx *Transaction) IsRollbackOnly() bool {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	return tx.rollbackOnly || tx.rollback
+}
+
+// SetRollbackOnly marks the transaction of the context as rollback-only,
+// so a nested scope can prevent the transaction from being committed without
+// returning an error through every layer.
+// It does nothing if the context doesn't have a transaction in progress.
+// See Transaction.SetRollbackOnly for details.
+func SetRollbackOnly(ctx context.Context) {
+	if tx, ok := transactionFrom(ctx); ok {
+		tx.SetRollbackOnly()
+	}
+}
+
+// IsRollbackOnly reports whether the transaction of the context can no longer be committed.
+// It returns false if the context doesn't have a transaction in progress.
+// See Transaction.IsRollbackOnly for details.
+func IsRollbackOnly(ctx context.Context) bool {
+	tx, ok := transactionFrom(ctx)
+	return ok && tx.IsRollbackOnly()
+}
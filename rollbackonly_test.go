@@ -0,0 +1,70 @@
+package transact
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sklyar/go-transact/txtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSetRollbackOnly(t *testing.T) {
+	db := txtest.NewDB(t)
+	manager := &Manager{
+		db:    db,
+		store: newStore(),
+	}
+
+	baseContext := context.Background()
+	txContext := txtest.WithContext(baseContext)
+
+	tx := txtest.NewTx(t)
+	db.On("Begin", txContext, nilTxOptions).Return(tx, nil)
+	tx.On("Rollback", txtest.MatchContext(txContext)).Return(nil)
+
+	var rolledBack bool
+	err := manager.BeginFunc(baseContext, func(ctx context.Context) error {
+		assert.False(t, IsRollbackOnly(ctx))
+		assert.NoError(t, manager.AfterRollback(ctx, func(_ context.Context) {
+			rolledBack = true
+		}))
+
+		// the nested scope dooms the transaction without returning an error.
+		err := manager.BeginFunc(ctx, func(ctx context.Context) error {
+			SetRollbackOnly(ctx)
+			return nil
+		})
+		assert.NoError(t, err)
+
+		assert.True(t, IsRollbackOnly(ctx))
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrRollbackOnly)
+	assert.True(t, rolledBack)
+	assert.Equal(t, 0, manager.store.Len())
+}
+
+func TestSetRollbackOnlyWithoutTransaction(t *testing.T) {
+	ctx := context.Background()
+	SetRollbackOnly(ctx)
+	assert.False(t, IsRollbackOnly(ctx))
+
+	// a suspended transaction is not marked.
+	db := txtest.NewDB(t)
+	manager := &Manager{
+		db:    db,
+		store: newStore(),
+	}
+
+	tx := txtest.NewTx(t)
+	db.On("Begin", mock.Anything, nilTxOptions).Return(tx, nil)
+	tx.On("Commit", mock.Anything).Return(nil)
+
+	err := manager.BeginFunc(ctx, func(ctx context.Context) error {
+		SetRollbackOnly(WithoutTransaction(ctx))
+		assert.False(t, IsRollbackOnly(ctx))
+		return nil
+	})
+	assert.NoError(t, err)
+}
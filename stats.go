@@ -0,0 +1,97 @@
+package transact
+
+import (
+	"database/sql"
+	"sync/atomic"
+	"time"
+
+	"github.com/sklyar/go-transact/txsql"
+)
+
+// Stats is a point-in-time view of the transactions of a manager.
+type Stats struct {
+	// OpenTransactions is the number of transactions in progress.
+	OpenTransactions int
+
+	// Transactions are the transactions in progress, oldest first.
+	Transactions []TransactionStats
+
+	// Committed is the number of transactions that have been committed.
+	Committed int64
+	// RolledBack is the number of transactions that have been rolled back.
+	RolledBack int64
+	// CommitFailed is the number of transactions that have failed to commit.
+	CommitFailed int64
+
+	// DB is the connection pool statistics of the database.
+	// It is zero if the database doesn't provide them.
+	DB sql.DBStats
+}
+
+// poolStatser is implemented by the databases providing the statistics of their connection pool,
+// such as the ones wrapping *sql.DB.
+type poolStatser interface {
+	Stats() sql.DBStats
+}
+
+// TransactionStats describes a transaction in progress.
+type TransactionStats struct {
+	// ID is the ID of the transaction.
+	ID string
+
+	// Age is the time since the transaction has begun.
+	Age time.Duration
+
+	// Depth is the number of nested scopes that have joined the transaction
+	// and have not been completed yet.
+	Depth int
+
+	// Options are the options the transaction has been started with.
+	// They are nil if no options have been provided.
+	Options *txsql.TxOptions
+}
+
+// Stats returns the statistics of the transactions begun by the manager
+// and of the connection pool of the database, if the database provides them.
+func (m *Manager) Stats() Stats {
+	txs := m.store.transactions()
+
+	stats := Stats{
+		OpenTransactions: len(txs),
+		Transactions:     make([]TransactionStats, 0, len(txs)),
+		Committed:        m.counters.committed.Load(),
+		RolledBack:       m.counters.rolledBack.Load(),
+		CommitFailed:     m.counters.commitFailed.Load(),
+	}
+	if db, ok := m.db.(poolStatser); ok {
+		stats.DB = db.Stats()
+	}
+	for _, tx := range txs {
+		stats.Transactions = append(stats.Transactions, TransactionStats{
+			ID:      tx.id,
+			Age:     time.Since(tx.startedAt),
+			Depth:   int(tx.scopes.Load()),
+			Options: tx.options,
+		})
+	}
+	return stats
+}
+
+// counters counts the completed transactions by outcome.
+type counters struct {
+	committed    atomic.Int64
+	rolledBack   atomic.Int64
+	commitFailed atomic.Int64
+}
+
+// add counts a transaction completed with the outcome.
+func (c *counters) add(outcome Outcome) {
+	switch outcome {
+	case OutcomeCommitted:
+		c.committed.Add(1)
+	case OutcomeRolledBack:
+		c.rolledBack.Add(1)
+	case OutcomeCommitFailed:
+		c.commitFailed.Add(1)
+	}
+}
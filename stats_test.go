@@ -0,0 +1,73 @@
+package transact
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/sklyar/go-transact/txsql"
+	"github.com/sklyar/go-transact/txtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// statsDB is a database providing the statistics of its connection pool.
+type statsDB struct {
+	*txtest.DB
+	stats sql.DBStats
+}
+
+func (db statsDB) Stats() sql.DBStats {
+	return db.stats
+}
+
+func TestManagerStats(t *testing.T) {
+	db := txtest.NewDB(t)
+	dbStats := sql.DBStats{OpenConnections: 3, InUse: 2, Idle: 1}
+	manager := &Manager{
+		db:    statsDB{DB: db, stats: dbStats},
+		store: newStore(),
+	}
+
+	tx := txtest.NewTx(t)
+	db.On("Begin", mock.Anything, mock.Anything).Return(tx, nil)
+	tx.On("Commit", mock.Anything).Return(nil).Once()
+	tx.On("Rollback", mock.Anything).Return(nil).Once()
+	tx.On("Commit", mock.Anything).Return(errors.New("some error")).Once()
+
+	assert.NoError(t, manager.BeginFunc(context.Background(), func(_ context.Context) error { return nil }))
+	assert.Error(t, manager.BeginFunc(context.Background(), func(_ context.Context) error { return errors.New("some error") }))
+	assert.Error(t, manager.BeginFunc(context.Background(), func(_ context.Context) error { return nil }))
+
+	ctx, _, err := manager.Begin(context.Background(), txsql.WithReadOnly())
+	require.NoError(t, err)
+	_, _, err = manager.Begin(ctx)
+	require.NoError(t, err)
+
+	stats := manager.Stats()
+	assert.Equal(t, 1, stats.OpenTransactions)
+	assert.Equal(t, int64(1), stats.Committed)
+	assert.Equal(t, int64(1), stats.RolledBack)
+	assert.Equal(t, int64(1), stats.CommitFailed)
+	assert.Equal(t, dbStats, stats.DB)
+
+	require.Len(t, stats.Transactions, 1)
+	assert.Equal(t, "4", stats.Transactions[0].ID)
+	assert.Equal(t, 1, stats.Transactions[0].Depth)
+	assert.Equal(t, &txsql.TxOptions{ReadOnly: true}, stats.Transactions[0].Options)
+	assert.Positive(t, stats.Transactions[0].Age)
+}
+
+func TestManagerStatsWithoutPoolStats(t *testing.T) {
+	manager := &Manager{
+		db:    txtest.NewDB(t),
+		store: newStore(),
+	}
+
+	// the pool statistics are optional for a database.
+	stats := manager.Stats()
+	assert.Zero(t, stats.DB)
+	assert.Zero(t, stats.OpenTransactions)
+}
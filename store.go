@@ -3,6 +3,7 @@ package transact
 import (
 	"context"
 	"errors"
+	"sort"
 	"sync"
 
 	"github.com/sklyar/go-transact/internal/txcontext"
@@ -32,7 +33,8 @@ func (s *store) Transaction(ctx context.Context) (*Transaction, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	return s.txs[tid], true
+	tx, ok := s.txs[tid]
+	return tx, ok
 }
 
 // Add adds the transaction to the store.
@@ -50,16 +52,11 @@ func (s *store) Add(tx *Transaction) error {
 	return nil
 }
 
-// Delete deletes the transaction from the store.
-func (s *store) Delete(ctx context.Context, tx *Transaction) error {
-	if txcontext.IsChild(ctx) {
-		return nil
-	}
-
+// remove removes the transaction with the given ID from the store.
+func (s *store) remove(tid string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	tid := tx.ID()
 	if _, ok := s.txs[tid]; !ok {
 		return errors.New("transaction not found")
 	}
@@ -69,6 +66,21 @@ func (s *store) Delete(ctx context.Context, tx *Transaction) error {
 	return nil
 }
 
+// transactions returns the transactions in the store, oldest first.
+func (s *store) transactions() []*Transaction {
+	s.mu.RLock()
+	txs := make([]*Transaction, 0, len(s.txs))
+	for _, tx := range s.txs {
+		txs = append(txs, tx)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(txs, func(i, j int) bool {
+		return txs[i].startedAt.Before(txs[j].startedAt)
+	})
+	return txs
+}
+
 // Len returns the number of transactions in the store.
 func (s *store) Len() int {
 	s.mu.RLock()
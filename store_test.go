@@ -56,48 +56,6 @@ func Test_store_Add(t *testing.T) {
 	assert.Error(t, s.Add(&Transaction{id: "1"}), "Expected error when adding transaction with same id")
 }
 
-func Test_store_Delete(t *testing.T) {
-	t.Parallel()
-
-	t.Run("delete a transaction", func(t *testing.T) {
-		s := newStore()
-		tx1 := &Transaction{id: "1"}
-		tx2 := &Transaction{id: "2"}
-
-		require.NoError(t, s.Add(tx1), "Expected no error when adding transaction")
-		require.NoError(t, s.Add(tx2), "Expected no error when adding transaction")
-		require.Equal(t, 2, len(s.txs), "Expected 2 transactions")
-
-		baseContext := context.Background()
-
-		ctx1 := txtest.WithContextValue(baseContext, "1", false)
-		assert.NoError(t, s.Delete(ctx1, tx1), "Expected no error when deleting transaction")
-
-		ctx2 := txtest.WithContextValue(baseContext, "2", false)
-		assert.NoError(t, s.Delete(ctx2, tx2), "Expected no error when deleting transaction")
-
-		assert.Equal(t, 0, len(s.txs), "Expected 0 transactions")
-	})
-
-	t.Run("delete a nested transaction", func(t *testing.T) {
-		s := newStore()
-		tx1 := &Transaction{id: "1"}
-		tx2 := &Transaction{id: "2"}
-
-		require.NoError(t, s.Add(tx1), "Expected no error when adding transaction")
-		require.NoError(t, s.Add(tx2), "Expected no error when adding transaction")
-		require.Equal(t, 2, len(s.txs), "Expected 2 transactions")
-
-		baseContext := context.Background()
-		childTxContext := txtest.WithContextValue(baseContext, "2", true)
-
-		// In this case, the transaction store shouldn`t delete the transaction.
-		// Because the transaction is a child transaction.
-		assert.NoError(t, s.Delete(childTxContext, tx2), "Expected no error when deleting transaction")
-		assert.Len(t, s.txs, 2, "Expected 2 transactions")
-	})
-}
-
 func Test_store_Len(t *testing.T) {
 	store := newStore()
 	assert.Equal(t, 0, store.Len(), "Expected 0 transactions")
@@ -108,6 +66,6 @@ func Test_store_Len(t *testing.T) {
 	_ = store.Add(&Transaction{id: "2"})
 	assert.Equal(t, 2, store.Len(), "Expected 2 transactions")
 
-	_ = store.Delete(context.Background(), &Transaction{id: "1"})
+	_ = store.remove("1")
 	assert.Equal(t, 1, store.Len(), "Expected 1 transaction")
 }
@@ -0,0 +1,12 @@
+package transact
+
+import "github.com/sklyar/go-transact/txsql"
+
+// WithTracer makes the manager trace the transactions it begins.
+// The context returned by Manager.Begin carries the context returned by the tracer,
+// so a database adapter traces the statements executed within the transaction as its children.
+func WithTracer(tracer txsql.Tracer) Option {
+	return func(m *Manager) {
+		m.tracer = tracer
+	}
+}
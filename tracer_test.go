@@ -0,0 +1,110 @@
+package transact
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sklyar/go-transact/txsql"
+	"github.com/sklyar/go-transact/txtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithTracer(t *testing.T) {
+	someErr := errors.New("some error")
+
+	tests := []struct {
+		name          string
+		setup         func(tx *txtest.Tx)
+		fnErr         error
+		wantCommitted bool
+		wantErr       error
+	}{
+		{
+			name: "committed",
+			setup: func(tx *txtest.Tx) {
+				tx.On("Commit", mock.Anything).Return(nil)
+			},
+			wantCommitted: true,
+		},
+		{
+			name: "rolled back",
+			setup: func(tx *txtest.Tx) {
+				tx.On("Rollback", mock.Anything).Return(nil)
+			},
+			fnErr:   someErr,
+			wantErr: someErr,
+		},
+		{
+			name: "commit failed",
+			setup: func(tx *txtest.Tx) {
+				tx.On("Commit", mock.Anything).Return(someErr)
+			},
+			wantErr: someErr,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracer := txtest.NewTracer()
+
+			db := txtest.NewDB(t)
+			manager := &Manager{
+				db:     db,
+				store:  newStore(),
+				tracer: tracer,
+			}
+
+			tx := txtest.NewTx(t)
+			db.On("Begin", mock.Anything, mock.Anything).Return(tx, nil)
+			tt.setup(tx)
+
+			_ = manager.BeginFunc(context.Background(), func(ctx context.Context) error {
+				// the statements are traced by the adapter with the context of the transaction.
+				ctx = tracer.StartQuery(ctx, txsql.QueryInfo{Query: "SELECT 1", TxID: "1"})
+				tracer.EndQuery(ctx, nil)
+				return tt.fnErr
+			}, txsql.WithReadOnly())
+
+			spans := tracer.Spans()
+			require.Len(t, spans, 2)
+
+			txSpan := spans[0]
+			assert.Equal(t, txtest.SpanTransaction, txSpan.Name)
+			assert.Equal(t, "1", txSpan.TxID)
+			assert.Equal(t, &txsql.TxOptions{ReadOnly: true}, txSpan.Options)
+			assert.True(t, txSpan.Ended)
+			assert.Equal(t, tt.wantCommitted, txSpan.Committed)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, txSpan.Err, tt.wantErr)
+			} else {
+				assert.NoError(t, txSpan.Err)
+			}
+
+			assert.Same(t, txSpan, spans[1].Parent)
+		})
+	}
+}
+
+func TestWithTracerBeginFails(t *testing.T) {
+	tracer := txtest.NewTracer()
+
+	db := txtest.NewDB(t)
+	manager := &Manager{
+		db:     db,
+		store:  newStore(),
+		tracer: tracer,
+	}
+
+	someErr := errors.New("some error")
+	db.On("Begin", mock.Anything, nilTxOptions).Return(nil, someErr)
+
+	_, _, err := manager.Begin(context.Background())
+	require.ErrorIs(t, err, someErr)
+
+	spans := tracer.Spans()
+	require.Len(t, spans, 1)
+	assert.True(t, spans[0].Ended)
+	assert.ErrorIs(t, spans[0].Err, someErr)
+}
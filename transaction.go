@@ -3,6 +3,10 @@ package transact
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/sklyar/go-transact/internal/txcontext"
 	"github.com/sklyar/go-transact/txsql"
@@ -12,8 +16,24 @@ var (
 	ErrNoTransaction     = errors.New("no transaction")
 	ErrClosedTransaction = errors.New("transaction is closed")
 
+	// ErrTransactionRequired is returned when a transaction with txsql.PropagationMandatory
+	// is started without an existing transaction in the context.
+	ErrTransactionRequired = errors.New("existing transaction is required")
+	// ErrTransactionNotAllowed is returned when a transaction with txsql.PropagationNever
+	// is started within an existing transaction.
+	ErrTransactionNotAllowed = errors.New("existing transaction is not allowed")
+	// ErrLongRunningTransaction is returned when a transaction has been rolled back by the watchdog
+	// because it has been open for too long.
+	ErrLongRunningTransaction = errors.New("transaction has been running for too long")
+	// ErrTransactionTimeout is returned when a transaction started with txsql.WithTimeout
+	// has not completed before its deadline and has been rolled back.
+	ErrTransactionTimeout = errors.New("transaction timed out")
+	// ErrRollbackOnly is returned when a transaction that has been marked as rollback-only,
+	// or has already been rolled back by a nested scope, is committed.
+	// The transaction is rolled back instead.
+	ErrRollbackOnly = errors.New("transaction has been marked for rollback and cannot be committed")
+
 	errCommittedTransaction = errors.New("operation failed: transaction has already been committed")
-	errMarkedForRollback    = errors.New("operation failed: transaction has been marked for rollback and cannot be committed")
 )
 
 // Transaction is a transaction wrapper.
@@ -22,8 +42,89 @@ type Transaction struct {
 
 	id string
 
+	// mu guards the state of the transaction, which can be changed concurrently
+	// by the timeout, the watchdog, or goroutines sharing the context of the transaction.
+	// Every state transition is decided while holding it.
+	mu       sync.Mutex
 	commit   bool
 	rollback bool
+	// timedOut is true if the timeout of the transaction has elapsed
+	// before the transaction has been completed.
+	timedOut bool
+	// cancelTimeout releases the resources of the transaction timeout.
+	cancelTimeout context.CancelFunc
+	// abortCause is the reason the transaction has been rolled back behind the back of its owner.
+	abortCause error
+	// rollbackCause is the reason the transaction has been rolled back, if known.
+	rollbackCause error
+	// rollbackOnce performs the rollback of the underlying transaction.
+	rollbackOnce sync.Once
+	// rollbackOnly is true if the transaction has been marked for rollback
+	// but has not been rolled back yet.
+	rollbackOnly bool
+	// killed is true if the transaction has been rolled back behind the back of its owner.
+	// Such a transaction is removed from the store only once its owner commits or rolls it back.
+	killed bool
+
+	// empty is true if the transaction represents a non-transactional scope.
+	empty bool
+
+	// store is the store the transaction has been added to.
+	// The transaction removes itself from the store once it is completed.
+	store     *store
+	completed bool
+	detached  bool
+
+	hooks hooks
+
+	metrics    Metrics
+	counters   *counters
+	statements atomic.Int64
+	// scopes is the number of nested scopes that have joined the transaction and are not completed.
+	scopes atomic.Int32
+
+	// tracer traces the transaction in the context returned by the tracer on begin.
+	tracer   txsql.Tracer
+	traceCtx context.Context
+
+	// watched is true if the transaction is watched by the watchdog.
+	watched       bool
+	beginStack    []uintptr
+	lastStatement atomic.Pointer[string]
+	reported      atomic.Bool
+
+	options      *txsql.TxOptions
+	startedAt    time.Time
+	interceptors []Interceptor
+}
+
+// owner is carried by the contexts of a transaction, so the transaction can be found from them.
+// It is garbage collected along with the last context of the transaction.
+type owner struct {
+	tx *Transaction
+}
+
+// withOwner returns a copy of the root context of the transaction carrying its owner.
+func withOwner(ctx context.Context, tx *Transaction) (context.Context, *owner) {
+	v, _ := txcontext.FromTx(ctx, tx.id)
+	o := &owner{tx: tx}
+	v.Owner = o
+	return txcontext.Wrap(ctx, v), o
+}
+
+// transactionFrom returns the transaction of the context that is in progress.
+// It returns false if the context doesn't have a transaction in progress.
+func transactionFrom(ctx context.Context) (*Transaction, bool) {
+	v, ok := txcontext.Current(ctx)
+	if !ok {
+		return nil, false
+	}
+
+	o, ok := v.Owner.(*owner)
+	if !ok {
+		return nil, false
+	}
+	return o.tx, true
 }
 
 // newTransaction creates a new transaction.
@@ -31,17 +132,35 @@ func newTransaction(id string, tx txsql.Tx) *Transaction {
 	return &Transaction{Tx: tx, id: id}
 }
 
+// newEmptyTransaction creates a transaction for a non-transactional scope.
+// Committing or rolling it back does nothing.
+func newEmptyTransaction() *Transaction {
+	return &Transaction{empty: true}
+}
+
 // Commit executes a transaction.
 // If the transaction is a child, it does nothing and the original context is returned.
+// If the child is backed by a savepoint, the savepoint is released instead.
+// If the transaction represents a non-transactional scope, it does nothing.
 // If the transaction has already been committed or has been marked for deletion,
 // it returns the original context along with the corresponding error (ErrNoTransaction or ErrClosedTransaction).
 // After a successful commit, the transaction is marked as done within the context.
 func (tx *Transaction) Commit(ctx context.Context) (context.Context, error) {
-	if txcontext.IsChild(ctx) {
+	if tx.empty {
+		return ctx, nil
+	}
+
+	return tx.intercept(ctx, OpCommit, nil, tx.commitScope)
+}
+
+// commitScope commits the scope of the context.
+func (tx *Transaction) commitScope(ctx context.Context) (context.Context, error) {
+	v, exists := txcontext.FromTx(ctx, tx.id)
+	if exists && v.Depth > 0 && v.Savepoint == "" {
+		tx.leaveScope(v)
 		return ctx, nil
 	}
 
-	v, exists := txcontext.From(ctx)
 	if !exists {
 		return ctx, ErrNoTransaction
 	}
@@ -49,28 +168,128 @@ func (tx *Transaction) Commit(ctx context.Context) (context.Context, error) {
 		return ctx, ErrClosedTransaction
 	}
 
-	if tx.commit {
+	if v.Savepoint != "" {
+		v.Done = true
+		tx.leaveScope(v)
+		return txcontext.Wrap(ctx, v), tx.Tx.ReleaseSavepoint(ctx, v.Savepoint)
+	}
+
+	tx.mu.Lock()
+	committed, rolledBack, timedOut := tx.commit, tx.rollback, tx.timeoutElapsed(ctx)
+	abortCause, rollbackOnly := tx.abortCause, tx.rollbackOnly
+	tx.mu.Unlock()
+
+	if committed {
 		// unexpected commit after commit.
 		return ctx, errCommittedTransaction
 	}
-	if tx.rollback {
+	if timedOut {
+		return tx.rollbackTimedOut(ctx)
+	}
+	if rolledBack {
 		// unexpected commit after rollback.
-		return ctx, errMarkedForRollback
+		// The underlying transaction has already been rolled back, so it can be completed.
+		rollbackErr := ErrRollbackOnly
+		if abortCause != nil {
+			rollbackErr = abortCause
+		}
+		// the transaction completes only once the rollback has returned.
+		// The error of the rollback is reported to the one that has requested it.
+		_ = tx.rollbackTx(ctx)
+		err := tx.complete(ctx, OutcomeRolledBack, nil)
+		if derr := tx.detach(); derr != nil {
+			err = errors.Join(err, derr)
+		}
+		if err != nil {
+			return ctx, errors.Join(rollbackErr, err)
+		}
+		return ctx, rollbackErr
+	}
+	if rollbackOnly {
+		// the transaction cannot be committed, so it is rolled back instead.
+		ctx, err := tx.rollbackFor(ctx, ErrRollbackOnly)
+		if err != nil {
+			return ctx, errors.Join(ErrRollbackOnly, err)
+		}
+		return ctx, ErrRollbackOnly
+	}
+
+	if err := tx.hooks.runBeforeCommit(ctx); err != nil {
+		// the commit is vetoed, so the transaction is rolled back instead.
+		ctx, rerr := tx.rollbackFor(ctx, err)
+		if rerr != nil {
+			return ctx, errors.Join(err, rerr)
+		}
+		return ctx, err
+	}
+
+	// the state may have changed while the hooks were running,
+	// so the commit is decided only if the transaction is still alive.
+	tx.mu.Lock()
+	committed, rolledBack, timedOut = tx.commit, tx.rollback, tx.timeoutElapsed(ctx)
+	abortCause = tx.abortCause
+	tx.commit = !committed && !rolledBack && !timedOut
+	tx.mu.Unlock()
+	switch {
+	case committed:
+		// concurrent commit.
+		return ctx, errCommittedTransaction
+	case timedOut:
+		return tx.rollbackTimedOut(ctx)
+	case rolledBack && abortCause != nil:
+		// the transaction has been rolled back behind the back of its owner.
+		_ = tx.rollbackTx(ctx)
+		if err := tx.detach(); err != nil {
+			return ctx, errors.Join(abortCause, err)
+		}
+		return ctx, abortCause
+	case rolledBack:
+		// concurrent rollback.
+		return ctx, ErrClosedTransaction
 	}
 
-	tx.commit = true
 	v.Done = true
-	return txcontext.Wrap(ctx, v), tx.Tx.Commit(ctx)
+
+	err := tx.Tx.Commit(ctx)
+	ctx = txcontext.Wrap(ctx, v)
+	outcome := OutcomeCommitted
+	if err != nil {
+		outcome = OutcomeCommitFailed
+	}
+	if cerr := tx.complete(ctx, outcome, err); cerr != nil {
+		err = errors.Join(err, cerr)
+	}
+	return ctx, err
 }
 
 // Rollback aborts a transaction.
-// If the transaction is a child, it does nothing and the original context is returned.
+// If the transaction is a child backed by a savepoint, only the changes made after
+// the savepoint are rolled back and the savepoint is released, so the parent transaction stays usable.
+// Otherwise, the whole transaction is rolled back, including the parent one.
+// If the transaction represents a non-transactional scope, it does nothing.
 // If the transaction doesn't exist in the context, it returns the original context along with ErrNoTransaction.
 // If the transaction has already been rolled back or marked as done,
 // it returns the original context along with ErrClosedTransaction.
 // Upon a successful rollback, the transaction is marked as done within the context.
 func (tx *Transaction) Rollback(ctx context.Context) (context.Context, error) {
-	v, exists := txcontext.From(ctx)
+	return tx.rollbackFor(ctx, nil)
+}
+
+// rollbackFor rolls back the scope of the context because of the cause error.
+func (tx *Transaction) rollbackFor(ctx context.Context, cause error) (context.Context, error) {
+	if tx.empty {
+		return ctx, nil
+	}
+
+	return tx.intercept(ctx, OpRollback, cause, func(ctx context.Context) (context.Context, error) {
+		return tx.rollbackScope(ctx, cause)
+	})
+}
+
+// rollbackScope rolls back the scope of the context because of the cause error, if any.
+// The cause is recorded only once the transaction is decided to be rolled back.
+func (tx *Transaction) rollbackScope(ctx context.Context, cause error) (context.Context, error) {
+	v, exists := txcontext.FromTx(ctx, tx.id)
 	if !exists {
 		return ctx, ErrNoTransaction
 	}
@@ -78,23 +297,301 @@ func (tx *Transaction) Rollback(ctx context.Context) (context.Context, error) {
 		return ctx, ErrClosedTransaction
 	}
 
-	if tx.commit {
+	tx.mu.Lock()
+	committed, rolledBack := tx.commit, tx.rollback
+	if v.Savepoint == "" && !committed {
+		tx.rollback = true
+		if tx.rollbackCause == nil {
+			tx.rollbackCause = cause
+		}
+	}
+	tx.mu.Unlock()
+
+	if committed {
 		// unexpected commit after commit.
 		return ctx, errCommittedTransaction
 	}
 
-	fn := tx.Tx.Rollback
-	if tx.rollback {
-		fn = func(_ context.Context) error { return nil }
-	} else {
-		tx.rollback = true
+	tx.leaveScope(v)
+	if v.Savepoint != "" {
+		v.Done = true
+		if rolledBack {
+			// the whole transaction has already been rolled back.
+			return txcontext.Wrap(ctx, v), nil
+		}
+		if err := tx.Tx.RollbackToSavepoint(ctx, v.Savepoint); err != nil {
+			return txcontext.Wrap(ctx, v), err
+		}
+		tx.hooks.rollbackToSavepoint(v.Savepoint)
+		// the savepoint outlives the rollback to it, so it is released,
+		// otherwise the savepoints of the failed scopes pile up until the transaction ends.
+		return txcontext.Wrap(ctx, v), tx.Tx.ReleaseSavepoint(ctx, v.Savepoint)
 	}
 
 	v.Done = true
-	return txcontext.Wrap(ctx, v), fn(ctx)
+
+	// if the transaction is being rolled back by someone else, wait for it.
+	err := tx.rollbackTx(ctx)
+	ctx = txcontext.Wrap(ctx, v)
+	if v.Depth == 0 {
+		if cerr := tx.complete(ctx, OutcomeRolledBack, nil); cerr != nil {
+			err = errors.Join(err, cerr)
+		}
+		if derr := tx.detach(); derr != nil {
+			err = errors.Join(err, derr)
+		}
+	}
+	return ctx, err
+}
+
+// rollbackTx rolls back the underlying transaction.
+// The rollback is performed only once: concurrent callers wait for it to return,
+// and the ones coming after it get no error.
+func (tx *Transaction) rollbackTx(ctx context.Context) error {
+	var err error
+	tx.rollbackOnce.Do(func() {
+		err = tx.Tx.Rollback(ctx)
+	})
+	return err
+}
+
+// intercept performs the operation on the scope of the context through the interceptors of the transaction.
+func (tx *Transaction) intercept(
+	ctx context.Context,
+	op Operation,
+	cause error,
+	fn func(ctx context.Context) (context.Context, error),
+) (context.Context, error) {
+	if len(tx.interceptors) == 0 {
+		return fn(ctx)
+	}
+
+	v, _ := txcontext.FromTx(ctx, tx.id)
+	info := InterceptorInfo{
+		Operation: op,
+		ID:        tx.id,
+		Depth:     v.Depth,
+		Savepoint: v.Savepoint,
+		Options:   tx.options,
+		StartedAt: tx.startedAt,
+		Cause:     cause,
+	}
+
+	out := ctx
+	err := intercept(ctx, tx.interceptors, info, func(ctx context.Context) error {
+		var err error
+		out, err = fn(ctx)
+		return err
+	})
+	return out, err
+}
+
+// rollbackTimedOut rolls back the transaction whose timeout has elapsed.
+// It returns ErrTransactionTimeout, joined with the rollback error if any.
+func (tx *Transaction) rollbackTimedOut(ctx context.Context) (context.Context, error) {
+	ctx, err := tx.rollbackFor(ctx, ErrTransactionTimeout)
+	if err != nil {
+		return ctx, errors.Join(ErrTransactionTimeout, err)
+	}
+	return ctx, ErrTransactionTimeout
+}
+
+// timeoutElapsed reports whether the timeout of the transaction has elapsed.
+// The context of the transaction is checked as well, since the deadline may pass
+// before the transaction is notified about it.
+// The caller must hold tx.mu.
+func (tx *Transaction) timeoutElapsed(ctx context.Context) bool {
+	if !tx.timedOut && !tx.commit && !tx.rollback && errors.Is(context.Cause(ctx), ErrTransactionTimeout) {
+		tx.timedOut = true
+	}
+	return tx.timedOut
+}
+
+// watchTimeout rolls back the transaction once the timeout of the context elapses,
+// unless the transaction has been completed by then.
+// The context must be the root context of the transaction, and tx.cancelTimeout must cancel it.
+func (tx *Transaction) watchTimeout(ctx context.Context) {
+	context.AfterFunc(ctx, func() {
+		if !errors.Is(context.Cause(ctx), ErrTransactionTimeout) {
+			// the context has been canceled, either on completion
+			// of the transaction or by the caller.
+			return
+		}
+
+		tx.mu.Lock()
+		expired := !tx.commit && !tx.rollback
+		tx.timedOut = tx.timedOut || expired
+		tx.mu.Unlock()
+		if !expired {
+			return
+		}
+
+		// nobody waits for the result, the caller finds out
+		// about the timeout on the next commit or rollback.
+		_, _ = tx.rollbackFor(context.WithoutCancel(ctx), ErrTransactionTimeout)
+	})
+}
+
+// rollbackOnCancel rolls back the transaction as soon as ctx is canceled,
+// unless the transaction has been completed by then, so the connection is released
+// without waiting for the next statement to fail. The owner gets an error wrapping
+// the error of ctx on the next commit.
+// The returned function stops watching ctx.
+func (tx *Transaction) rollbackOnCancel(ctx context.Context) func() bool {
+	return context.AfterFunc(ctx, func() {
+		cause := ctx.Err()
+		if c := context.Cause(ctx); !errors.Is(c, cause) {
+			cause = fmt.Errorf("%w: %w", cause, c)
+		}
+		// the rollback keeps the values of ctx for the interceptors and the hooks.
+		tx.kill(context.WithoutCancel(ctx), cause)
+	})
+}
+
+// enterScope records that a nested scope has joined the transaction.
+func (tx *Transaction) enterScope() {
+	tx.scopes.Add(1)
+}
+
+// leaveScope records that the scope of the value has been completed if it is a nested one.
+func (tx *Transaction) leaveScope(v txcontext.Value) {
+	if v.Depth > 0 && tx.scopes.Add(-1) < 0 {
+		// the scope has been completed more than once.
+		tx.scopes.Store(0)
+	}
+}
+
+// abort rolls back the scope of the context after its transaction function panicked.
+// A child scope that joined its parent is only marked for rollback,
+// so the transaction is rolled back by the root scope.
+func (tx *Transaction) abort(ctx context.Context, cause error) error {
+	if v, _ := txcontext.FromTx(ctx, tx.id); v.Depth > 0 && v.Savepoint == "" {
+		tx.mu.Lock()
+		tx.rollbackOnly = true
+		tx.mu.Unlock()
+		tx.leaveScope(v)
+		return nil
+	}
+
+	_, err := tx.rollbackFor(ctx, cause)
+	return err
+}
+
+// complete finishes the root transaction once its outcome is known.
+// It removes the transaction from the store, reports the outcome to the metrics
+// and the tracer, and calls the hooks registered for the outcome.
+// The error is the commit error, if any.
+// It does nothing if the transaction has already been completed.
+func (tx *Transaction) complete(ctx context.Context, outcome Outcome, err error) error {
+	tx.mu.Lock()
+	completed, killed := tx.completed, tx.killed
+	tx.completed = true
+	if err == nil {
+		err = tx.rollbackCause
+	}
+	tx.mu.Unlock()
+	if completed {
+		return nil
+	}
+
+	if tx.cancelTimeout != nil {
+		tx.cancelTimeout()
+	}
+
+	if tx.tracer != nil {
+		tx.tracer.EndTransaction(tx.traceCtx, outcome == OutcomeCommitted, err)
+	}
+
+	var derr error
+	if !killed {
+		derr = tx.detach()
+	}
+	if tx.counters != nil {
+		tx.counters.add(outcome)
+	}
+	if tx.metrics != nil {
+		tx.metrics.TransactionCompleted(outcome, time.Since(tx.startedAt), int(tx.statements.Load()))
+	}
+	tx.hooks.runAfter(ctx, outcome == OutcomeCommitted)
+	return derr
+}
+
+// isCompleted reports whether the transaction has been completed.
+func (tx *Transaction) isCompleted() bool {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	return tx.completed
+}
+
+// detach removes the completed transaction from the store.
+// It does nothing if the transaction has not been added to a store or has already been removed from it.
+func (tx *Transaction) detach() error {
+	tx.mu.Lock()
+	detached := tx.detached
+	tx.detached = true
+	tx.mu.Unlock()
+	if detached || tx.store == nil {
+		return nil
+	}
+
+	if err := tx.store.remove(tx.id); err != nil {
+		return fmt.Errorf("failed to delete transaction from store: %w", err)
+	}
+	return nil
+}
+
+// executing records the statement executed within the transaction.
+func (tx *Transaction) executing(query string) {
+	tx.statements.Add(1)
+	if tx.watched {
+		tx.lastStatement.Store(&query)
+	}
+}
+
+// Exec executes a query that doesn't return rows within the transaction.
+func (tx *Transaction) Exec(ctx context.Context, query string, args ...any) (txsql.Result, error) {
+	tx.executing(query)
+	return tx.Tx.Exec(ctx, query, args...)
+}
+
+// Query executes a query that returns rows within the transaction.
+func (tx *Transaction) Query(ctx context.Context, query string, args ...any) (txsql.Rows, error) {
+	tx.executing(query)
+	return tx.Tx.Query(ctx, query, args...)
+}
+
+// QueryRow executes a query that is expected to return at most one row within the transaction.
+func (tx *Transaction) QueryRow(ctx context.Context, query string, args ...any) txsql.Row {
+	tx.executing(query)
+	return tx.Tx.QueryRow(ctx, query, args...)
+}
+
+// Prepare creates a prepared statement for use within the transaction.
+func (tx *Transaction) Prepare(ctx context.Context, query string) (txsql.Stmt, error) {
+	tx.executing(query)
+	return tx.Tx.Prepare(ctx, query)
 }
 
 // ID returns a transaction ID.
 func (tx *Transaction) ID() string {
 	return tx.id
 }
+
+// TransactionID returns the ID of the transaction of the context.
+// It returns false if the context doesn't have an active transaction.
+func TransactionID(ctx context.Context) (string, bool) {
+	return txcontext.ID(ctx)
+}
+
+// WithoutTransaction returns a copy of the context that doesn't carry the transaction of ctx.
+// The values, deadline and cancellation of ctx are kept, so statements executed with
+// the returned context run outside the transaction, in auto-commit mode, and persist
+// even if the transaction is rolled back. A transaction begun with the returned context
+// is a new root transaction rather than a child of the hidden one.
+func WithoutTransaction(ctx context.Context) context.Context {
+	if _, active := txcontext.ID(ctx); !active {
+		return ctx
+	}
+	return txcontext.Suspend(ctx)
+}
@@ -3,11 +3,15 @@ package transact
 import (
 	"context"
 	"errors"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/sklyar/go-transact/internal/txcontext"
+	"github.com/sklyar/go-transact/txsql"
 	"github.com/sklyar/go-transact/txtest"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestTransaction_Commit(t *testing.T) {
@@ -16,6 +20,7 @@ func TestTransaction_Commit(t *testing.T) {
 	ctx := context.Background()
 	txContext := txtest.WithContext(ctx)
 	childTxContext := txtest.WithChildContext(txContext)
+	savepointTxContext := txtest.WithSavepointContext(txContext, "sp_1")
 
 	errTest := errors.New("test error")
 
@@ -65,6 +70,15 @@ func TestTransaction_Commit(t *testing.T) {
 			wantCtx: childTxContext,
 			wantErr: nil,
 		},
+		{
+			name: "transaction is a child with savepoint",
+			ctx:  savepointTxContext,
+			tx:   &Transaction{id: "id"},
+			setup: func(sqlTx *txtest.Tx) {
+				sqlTx.On("ReleaseSavepoint", savepointTxContext, "sp_1").Return(nil)
+			},
+			wantCtx: setContextAsDone(t, savepointTxContext),
+		},
 		{
 			name:    "transaction is committed",
 			ctx:     txContext,
@@ -73,11 +87,27 @@ func TestTransaction_Commit(t *testing.T) {
 			wantErr: errCommittedTransaction,
 		},
 		{
-			name:    "transaction is marked for rollback",
-			ctx:     txContext,
-			tx:      &Transaction{id: "id", rollback: true},
+			name: "transaction is marked for rollback",
+			ctx:  txContext,
+			tx:   &Transaction{id: "id", rollback: true},
+			setup: func(sqlTx *txtest.Tx) {
+				// the rollback requested by someone else has not been performed yet,
+				// so the commit performs it before the transaction completes.
+				sqlTx.On("Rollback", txContext).Return(nil)
+			},
 			wantCtx: txContext,
-			wantErr: errMarkedForRollback,
+			wantErr: ErrRollbackOnly,
+		},
+		{
+			name: "transaction is marked as rollback-only",
+			ctx:  txContext,
+			tx:   &Transaction{id: "id", rollbackOnly: true},
+			setup: func(sqlTx *txtest.Tx) {
+				sqlTx.On("Rollback", txContext).Return(nil)
+			},
+			// the transaction is rolled back instead of being committed.
+			wantCtx: setContextAsDone(t, txContext),
+			wantErr: ErrRollbackOnly,
 		},
 		{
 			name: "commit fails",
@@ -131,6 +161,7 @@ func TestTransaction_Rollback(t *testing.T) {
 	ctx := context.Background()
 	txContext := txtest.WithContext(ctx)
 	childTxContext := txtest.WithChildContext(txContext)
+	savepointTxContext := txtest.WithSavepointContext(txContext, "sp_1")
 
 	errTest := errors.New("test error")
 
@@ -171,6 +202,18 @@ func TestTransaction_Rollback(t *testing.T) {
 			wantDone:     true,
 			wantRollback: true,
 		},
+		{
+			name: "rollback to savepoint for child transaction",
+			ctx:  savepointTxContext,
+			tx:   &Transaction{id: "id"},
+			setup: func(sqlTx *txtest.Tx) {
+				sqlTx.On("RollbackToSavepoint", savepointTxContext, "sp_1").Return(nil)
+				sqlTx.On("ReleaseSavepoint", savepointTxContext, "sp_1").Return(nil)
+			},
+			// the parent transaction must stay usable.
+			wantCtx:  setContextAsDone(t, savepointTxContext),
+			wantDone: true,
+		},
 		{
 			name:    "no transaction",
 			ctx:     ctx,
@@ -232,6 +275,71 @@ func TestTransaction_Rollback(t *testing.T) {
 	}
 }
 
+func TestTransaction_ConcurrentCompletion(t *testing.T) {
+	t.Parallel()
+
+	txContext := txtest.WithContext(context.Background())
+
+	var calls atomic.Int32
+	sqlTx := txtest.NewTx(t)
+	sqlTx.On("Commit", mock.Anything).Run(func(mock.Arguments) { calls.Add(1) }).Return(nil).Maybe()
+	sqlTx.On("Rollback", mock.Anything).Run(func(mock.Arguments) { calls.Add(1) }).Return(nil).Maybe()
+
+	tx := newTransaction("id", sqlTx)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(commit bool) {
+			defer wg.Done()
+
+			if commit {
+				_, _ = tx.Commit(txContext)
+			} else {
+				_, _ = tx.Rollback(txContext)
+			}
+		}(i%2 == 0)
+	}
+	wg.Wait()
+
+	// the outcome is decided exactly once.
+	assert.Equal(t, int32(1), calls.Load())
+	assert.NotEqual(t, tx.commit, tx.rollback)
+}
+
+func TestTransaction_RollbackLosingToCommit(t *testing.T) {
+	t.Parallel()
+
+	txContext := txtest.WithContextValue(context.Background(), "id", false)
+	errTest := errors.New("test error")
+
+	sqlTx := txtest.NewTx(t)
+	sqlTx.On("Commit", mock.Anything).Return(nil)
+
+	tracer := txtest.NewTracer()
+	tx := newTransaction("id", sqlTx)
+	tx.tracer = tracer
+	tx.traceCtx = tracer.StartTransaction(context.Background(), txsql.TransactionInfo{ID: "id"})
+	// the transaction is committed while the rollback is on its way.
+	tx.interceptors = []Interceptor{func(ctx context.Context, info InterceptorInfo, next Handler) error {
+		if info.Operation == OpRollback {
+			_, err := tx.Commit(txContext)
+			assert.NoError(t, err)
+		}
+		return next(ctx)
+	}}
+
+	_, err := tx.rollbackFor(txContext, errTest)
+	assert.ErrorIs(t, err, errCommittedTransaction)
+
+	// the committed transaction is not reported as failed with the cause of the rollback.
+	spans := tracer.Spans()
+	if assert.Len(t, spans, 1) {
+		assert.True(t, spans[0].Committed)
+		assert.NoError(t, spans[0].Err)
+	}
+}
+
 func setContextAsDone(t *testing.T, ctx context.Context) context.Context {
 	t.Helper()
 
@@ -0,0 +1,127 @@
+// Package txexpvar publishes transaction metrics with the expvar package.
+//
+// The metrics are installed on the manager with transact.WithMetrics:
+//
+//	txManager, db, err := transact.NewManager(adapterFactory,
+//		transact.WithMetrics(txexpvar.New("transactions")))
+package txexpvar
+
+import (
+	"expvar"
+	"strconv"
+	"time"
+
+	"github.com/sklyar/go-transact"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, of the transaction duration buckets used by default.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Metrics is a transact.Metrics that publishes the metrics as an expvar.Map
+// with the following variables:
+//
+//   - active: the number of transactions in progress;
+//   - begun: the number of begun transactions;
+//   - committed, rolled_back and commit_failed: the number of completed transactions by outcome;
+//   - statements: the number of statements executed within the completed transactions;
+//   - duration_seconds: the total duration of the completed transactions;
+//   - duration_buckets: the number of completed transactions by duration, keyed by
+//     the upper bound of the bucket in seconds, with "+Inf" for the longest ones.
+//     The buckets are cumulative, so a transaction is counted in every bucket it fits in.
+type Metrics struct {
+	vars *expvar.Map
+
+	active          *expvar.Int
+	begun           *expvar.Int
+	statements      *expvar.Int
+	durationSeconds *expvar.Float
+	durationBuckets *expvar.Map
+
+	buckets     []float64
+	bucketNames []string
+}
+
+// Option configures Metrics.
+type Option func(m *Metrics)
+
+// WithBuckets sets the upper bounds, in seconds, of the transaction duration buckets.
+// The bounds must be sorted in increasing order.
+func WithBuckets(buckets ...float64) Option {
+	return func(m *Metrics) {
+		m.buckets = buckets
+	}
+}
+
+// New creates metrics published under the given name.
+// Like expvar.Publish, it panics if the name is already in use.
+func New(name string, opts ...Option) *Metrics {
+	m := NewUnpublished(opts...)
+	expvar.Publish(name, m.vars)
+	return m
+}
+
+// NewUnpublished creates metrics that are not published.
+// They can be published later with expvar.Publish(name, m.Vars()).
+func NewUnpublished(opts ...Option) *Metrics {
+	m := &Metrics{
+		vars:            new(expvar.Map),
+		active:          new(expvar.Int),
+		begun:           new(expvar.Int),
+		statements:      new(expvar.Int),
+		durationSeconds: new(expvar.Float),
+		durationBuckets: new(expvar.Map),
+		buckets:         DefaultBuckets,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	m.vars.Set("active", m.active)
+	m.vars.Set("begun", m.begun)
+	for _, outcome := range []transact.Outcome{
+		transact.OutcomeCommitted,
+		transact.OutcomeRolledBack,
+		transact.OutcomeCommitFailed,
+	} {
+		m.vars.Set(string(outcome), new(expvar.Int))
+	}
+	m.vars.Set("statements", m.statements)
+	m.vars.Set("duration_seconds", m.durationSeconds)
+	m.vars.Set("duration_buckets", m.durationBuckets)
+
+	m.bucketNames = make([]string, len(m.buckets))
+	for i, bucket := range m.buckets {
+		m.bucketNames[i] = strconv.FormatFloat(bucket, 'g', -1, 64)
+		m.durationBuckets.Set(m.bucketNames[i], new(expvar.Int))
+	}
+	m.durationBuckets.Set("+Inf", new(expvar.Int))
+
+	return m
+}
+
+// Vars returns the map of the variables of the metrics.
+func (m *Metrics) Vars() *expvar.Map {
+	return m.vars
+}
+
+// TransactionBegun implements transact.Metrics.
+func (m *Metrics) TransactionBegun() {
+	m.active.Add(1)
+	m.begun.Add(1)
+}
+
+// TransactionCompleted implements transact.Metrics.
+func (m *Metrics) TransactionCompleted(outcome transact.Outcome, duration time.Duration, statements int) {
+	m.active.Add(-1)
+	m.vars.Add(string(outcome), 1)
+	m.statements.Add(int64(statements))
+
+	seconds := duration.Seconds()
+	m.durationSeconds.Add(seconds)
+	for i, bucket := range m.buckets {
+		if seconds <= bucket {
+			m.durationBuckets.Add(m.bucketNames[i], 1)
+		}
+	}
+	m.durationBuckets.Add("+Inf", 1)
+}
@@ -0,0 +1,48 @@
+package txexpvar
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sklyar/go-transact"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// published is the number of variables published by the tests,
+// so each run publishes a variable of its own.
+var published atomic.Int32
+
+func TestNew(t *testing.T) {
+	name := fmt.Sprintf("test_transactions_%d", published.Add(1))
+
+	m := New(name)
+	assert.Same(t, m.Vars(), expvar.Get(name))
+}
+
+func TestMetrics(t *testing.T) {
+	m := NewUnpublished(WithBuckets(0.1, 1))
+
+	m.TransactionBegun()
+	m.TransactionBegun()
+	m.TransactionBegun()
+	m.TransactionCompleted(transact.OutcomeCommitted, 50*time.Millisecond, 3)
+	m.TransactionCompleted(transact.OutcomeRolledBack, 2*time.Second, 1)
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal([]byte(m.Vars().String()), &got))
+	assert.Equal(t, map[string]any{
+		"active":           1.0,
+		"begun":            3.0,
+		"committed":        1.0,
+		"rolled_back":      1.0,
+		"commit_failed":    0.0,
+		"statements":       4.0,
+		"duration_seconds": 2.05,
+		"duration_buckets": map[string]any{"0.1": 1.0, "1": 1.0, "+Inf": 2.0},
+	}, got)
+}
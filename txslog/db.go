@@ -0,0 +1,141 @@
+package txslog
+
+import (
+	"context"
+	"log/slog"
+	"math/rand"
+	"time"
+
+	"github.com/sklyar/go-transact"
+	"github.com/sklyar/go-transact/txsql"
+)
+
+// WithRedactedArgs makes the statement logger log the arguments of statements.
+// The redact function receives the statement and its arguments, and returns
+// the values to be logged in place of the arguments, so secrets never reach the logs.
+// Without this option, the arguments are not logged at all.
+func WithRedactedArgs(redact func(query string, args []any) []any) Option {
+	return func(o *options) {
+		o.redact = redact
+	}
+}
+
+// WithSampleRate makes the statement logger log only the given fraction,
+// from 0 to 1, of the successful statements. Failed statements are always logged.
+func WithSampleRate(rate float64) Option {
+	return func(o *options) {
+		o.sampleRate = rate
+	}
+}
+
+// WithSlowThreshold makes the statement logger log only the successful statements
+// that took at least the given duration. Failed statements are always logged.
+func WithSlowThreshold(d time.Duration) Option {
+	return func(o *options) {
+		o.slowThreshold = d
+	}
+}
+
+// DB is a txsql.DB that logs the executed statements.
+type DB struct {
+	txsql.DB
+
+	logger *slog.Logger
+	opts   options
+}
+
+// WrapDB wraps the database so every Exec, Query, QueryRow and Prepare call is logged
+// with the statement, its duration, the number of affected rows, the error
+// and the ID of the enclosing transaction if there is one.
+// The statements executed through a prepared statement are not logged.
+func WrapDB(db txsql.DB, logger *slog.Logger, opts ...Option) *DB {
+	return &DB{
+		DB:     db,
+		logger: logger,
+		opts:   newOptions(opts),
+	}
+}
+
+func (db *DB) Exec(ctx context.Context, query string, args ...any) (txsql.Result, error) {
+	start := time.Now()
+	res, err := db.DB.Exec(ctx, query, args...)
+
+	rowsAffected := int64(-1)
+	if err == nil {
+		if n, rerr := res.RowsAffected(); rerr == nil {
+			rowsAffected = n
+		}
+	}
+
+	db.log(ctx, "sql exec", query, args, start, rowsAffected, err)
+	return res, err
+}
+
+func (db *DB) Query(ctx context.Context, query string, args ...any) (txsql.Rows, error) {
+	start := time.Now()
+	rows, err := db.DB.Query(ctx, query, args...)
+	db.log(ctx, "sql query", query, args, start, -1, err)
+	return rows, err
+}
+
+func (db *DB) QueryRow(ctx context.Context, query string, args ...any) txsql.Row {
+	start := time.Now()
+	row := db.DB.QueryRow(ctx, query, args...)
+	db.log(ctx, "sql query row", query, args, start, -1, row.Err())
+	return row
+}
+
+func (db *DB) Prepare(ctx context.Context, query string) (txsql.Stmt, error) {
+	start := time.Now()
+	stmt, err := db.DB.Prepare(ctx, query)
+	db.log(ctx, "sql prepare", query, nil, start, -1, err)
+	return stmt, err
+}
+
+// log logs the statement unless it is filtered out by the sampling and the slow threshold.
+// The number of affected rows is logged if it is not negative.
+func (db *DB) log(
+	ctx context.Context,
+	msg, query string,
+	args []any,
+	start time.Time,
+	rowsAffected int64,
+	err error,
+) {
+	duration := time.Since(start)
+
+	level := db.opts.level
+	if err != nil {
+		level = db.opts.errorLevel
+	} else if duration < db.opts.slowThreshold || !db.sampled() {
+		return
+	}
+	if !db.logger.Enabled(ctx, level) {
+		return
+	}
+
+	keys := db.opts.keys
+	attrs := make([]slog.Attr, 0, 6)
+	attrs = append(attrs, slog.String(keys.SQL, query))
+	if db.opts.redact != nil && len(args) > 0 {
+		attrs = append(attrs, slog.Any(keys.Args, db.opts.redact(query, args)))
+	}
+	attrs = append(attrs, slog.Duration(keys.Duration, duration))
+	if rowsAffected >= 0 {
+		attrs = append(attrs, slog.Int64(keys.RowsAffected, rowsAffected))
+	}
+	if id, ok := transact.TransactionID(ctx); ok {
+		attrs = append(attrs, slog.String(keys.ID, id))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String(keys.Error, err.Error()))
+	}
+
+	db.logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// sampled reports whether a successful statement is picked by the sampling.
+func (db *DB) sampled() bool {
+	rate := db.opts.sampleRate
+	return rate >= 1 || rate > 0 && rand.Float64() < rate //nolint:gosec // sampling doesn't need a secure random.
+}
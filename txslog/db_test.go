@@ -0,0 +1,112 @@
+package txslog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/sklyar/go-transact/txtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDB(t *testing.T) {
+	someErr := errors.New("some error")
+
+	tests := []struct {
+		name  string
+		opts  []Option
+		ctx   context.Context
+		setup func(db *txtest.DB, res *txtest.Result)
+		want  map[string]any
+	}{
+		{
+			name: "exec within transaction",
+			ctx:  txtest.WithContext(context.Background()),
+			setup: func(db *txtest.DB, res *txtest.Result) {
+				db.On("Exec", txtest.WithContext(context.Background()), "UPDATE users SET password = $1", "secret").Return(res, nil)
+				res.On("RowsAffected").Return(int64(3), nil)
+			},
+			want: map[string]any{
+				"level": "DEBUG", "msg": "sql exec", "sql": "UPDATE users SET password = $1", "rows_affected": 3.0, "tx_id": "1",
+			},
+		},
+		{
+			name: "redacted arguments",
+			opts: []Option{WithRedactedArgs(func(_ string, args []any) []any {
+				redacted := make([]any, len(args))
+				for i := range args {
+					redacted[i] = "***"
+				}
+				return redacted
+			})},
+			ctx: context.Background(),
+			setup: func(db *txtest.DB, res *txtest.Result) {
+				db.On("Exec", context.Background(), "UPDATE users SET password = $1", "secret").Return(res, nil)
+				res.On("RowsAffected").Return(int64(1), nil)
+			},
+			want: map[string]any{
+				"msg": "sql exec", "args": []any{"***"}, "rows_affected": 1.0, "tx_id": nil,
+			},
+		},
+		{
+			name: "fast statement below slow threshold",
+			opts: []Option{WithSlowThreshold(time.Hour)},
+			ctx:  context.Background(),
+			setup: func(db *txtest.DB, res *txtest.Result) {
+				db.On("Exec", context.Background(), "UPDATE users SET password = $1", "secret").Return(res, nil)
+				res.On("RowsAffected").Return(int64(1), nil)
+			},
+		},
+		{
+			name: "failed statement is always logged",
+			opts: []Option{WithSlowThreshold(time.Hour), WithSampleRate(0)},
+			ctx:  context.Background(),
+			setup: func(db *txtest.DB, _ *txtest.Result) {
+				db.On("Exec", context.Background(), "UPDATE users SET password = $1", "secret").Return(nil, someErr)
+			},
+			want: map[string]any{
+				"level": "ERROR", "msg": "sql exec", "error": "some error", "args": nil, "rows_affected": nil,
+			},
+		},
+		{
+			name: "statement not sampled",
+			opts: []Option{WithSampleRate(0)},
+			ctx:  context.Background(),
+			setup: func(db *txtest.DB, res *txtest.Result) {
+				db.On("Exec", context.Background(), "UPDATE users SET password = $1", "secret").Return(res, nil)
+				res.On("RowsAffected").Return(int64(1), nil)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+			mockDB := txtest.NewDB(t)
+			res := txtest.NewResult(t)
+			tt.setup(mockDB, res)
+
+			db := WrapDB(mockDB, logger, tt.opts...)
+			_, _ = db.Exec(tt.ctx, "UPDATE users SET password = $1", "secret")
+
+			if tt.want == nil {
+				assert.Empty(t, buf.String())
+				return
+			}
+
+			var got map[string]any
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+			assert.Contains(t, got, "duration")
+			assert.NotContains(t, buf.String(), "secret")
+			for k, v := range tt.want {
+				assert.Equal(t, v, got[k], k)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+// Package txslog logs the lifecycle of transactions and the executed statements with log/slog.
+//
+// The lifecycle logger is installed on the manager as an interceptor,
+// and the statement logger wraps the database returned by the manager:
+//
+//	txManager, db, err := transact.NewManager(adapterFactory,
+//		transact.WithInterceptors(txslog.New(slog.Default())))
+//	db = txslog.WrapDB(db, slog.Default(), txslog.WithSlowThreshold(100*time.Millisecond))
+package txslog
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/sklyar/go-transact"
+	"github.com/sklyar/go-transact/txsql"
+)
+
+// Keys are the attribute names of the logged records.
+type Keys struct {
+	// ID is the key of the transaction ID.
+	ID string
+	// Depth is the key of the nesting depth of the transaction scope.
+	Depth string
+	// Isolation is the key of the requested isolation level.
+	Isolation string
+	// ReadOnly is the key of the read-only flag.
+	ReadOnly string
+	// Duration is the key of the duration. On begin, it is the time the transaction took to start.
+	// On commit and rollback, it is the time since the root transaction has been started.
+	Duration string
+	// Error is the key of the error the operation failed with.
+	Error string
+	// Cause is the key of the error the transaction has been rolled back for.
+	Cause string
+	// SQL is the key of the statement.
+	SQL string
+	// Args is the key of the redacted statement arguments.
+	Args string
+	// RowsAffected is the key of the number of rows affected by the statement.
+	RowsAffected string
+}
+
+// DefaultKeys are the attribute names used by default.
+var DefaultKeys = Keys{
+	ID:        "tx_id",
+	Depth:     "tx_depth",
+	Isolation: "tx_isolation",
+	ReadOnly:  "tx_read_only",
+	Duration:  "duration",
+	Error:     "error",
+	Cause:     "cause",
+
+	SQL:          "sql",
+	Args:         "args",
+	RowsAffected: "rows_affected",
+}
+
+// Option configures the logging interceptor.
+type Option func(o *options)
+
+type options struct {
+	level      slog.Level
+	errorLevel slog.Level
+	keys       Keys
+
+	redact        func(query string, args []any) []any
+	sampleRate    float64
+	slowThreshold time.Duration
+}
+
+// newOptions returns the options with the defaults applied.
+func newOptions(opts []Option) options {
+	o := options{
+		level:      slog.LevelDebug,
+		errorLevel: slog.LevelError,
+		keys:       DefaultKeys,
+		sampleRate: 1,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithLevel sets the level of the records of successful operations.
+// The default level is slog.LevelDebug.
+func WithLevel(level slog.Level) Option {
+	return func(o *options) {
+		o.level = level
+	}
+}
+
+// WithErrorLevel sets the level of the records of failed operations
+// and of rollbacks caused by an error. The default level is slog.LevelError.
+func WithErrorLevel(level slog.Level) Option {
+	return func(o *options) {
+		o.errorLevel = level
+	}
+}
+
+// WithKeys sets the attribute names of the records.
+// Empty names are replaced with the ones of DefaultKeys.
+func WithKeys(keys Keys) Option {
+	return func(o *options) {
+		o.keys = Keys{
+			ID:        orDefault(keys.ID, DefaultKeys.ID),
+			Depth:     orDefault(keys.Depth, DefaultKeys.Depth),
+			Isolation: orDefault(keys.Isolation, DefaultKeys.Isolation),
+			ReadOnly:  orDefault(keys.ReadOnly, DefaultKeys.ReadOnly),
+			Duration:  orDefault(keys.Duration, DefaultKeys.Duration),
+			Error:     orDefault(keys.Error, DefaultKeys.Error),
+			Cause:     orDefault(keys.Cause, DefaultKeys.Cause),
+
+			SQL:          orDefault(keys.SQL, DefaultKeys.SQL),
+			Args:         orDefault(keys.Args, DefaultKeys.Args),
+			RowsAffected: orDefault(keys.RowsAffected, DefaultKeys.RowsAffected),
+		}
+	}
+}
+
+// New returns an interceptor that logs the begin, commit and rollback
+// of every transaction scope to the logger.
+func New(logger *slog.Logger, opts ...Option) transact.Interceptor {
+	o := newOptions(opts)
+
+	return func(ctx context.Context, info transact.InterceptorInfo, next transact.Handler) error {
+		start := time.Now()
+		err := next(ctx)
+
+		level := o.level
+		if err != nil || info.Cause != nil {
+			level = o.errorLevel
+		}
+		if !logger.Enabled(ctx, level) {
+			return err
+		}
+
+		duration := time.Since(info.StartedAt)
+		if info.Operation == transact.OpBegin {
+			duration = time.Since(start)
+		}
+
+		var txOptions txsql.TxOptions
+		if info.Options != nil {
+			txOptions = *info.Options
+		}
+
+		attrs := make([]slog.Attr, 0, 7)
+		attrs = append(attrs,
+			slog.String(o.keys.ID, info.ID),
+			slog.Int(o.keys.Depth, info.Depth),
+			slog.String(o.keys.Isolation, txOptions.Isolation.String()),
+			slog.Bool(o.keys.ReadOnly, txOptions.ReadOnly),
+			slog.Duration(o.keys.Duration, duration),
+		)
+		if info.Cause != nil {
+			attrs = append(attrs, slog.String(o.keys.Cause, info.Cause.Error()))
+		}
+		if err != nil {
+			attrs = append(attrs, slog.String(o.keys.Error, err.Error()))
+		}
+
+		logger.LogAttrs(ctx, level, message(info, err), attrs...)
+		return err
+	}
+}
+
+// message returns the message of the record for the outcome of the operation.
+func message(info transact.InterceptorInfo, err error) string {
+	op := info.Operation
+	if err != nil {
+		return "transaction " + string(op) + " failed"
+	}
+
+	switch op {
+	case transact.OpBegin:
+		return "transaction started"
+	case transact.OpCommit:
+		if joined(info) {
+			// nothing is committed until the root scope commits.
+			return "transaction scope completed"
+		}
+		return "transaction committed"
+	case transact.OpRollback:
+		return "transaction rolled back"
+	default:
+		return "transaction " + string(op)
+	}
+}
+
+// joined reports whether the scope of the operation has joined its parent
+// rather than being backed by a savepoint.
+func joined(info transact.InterceptorInfo) bool {
+	return info.Depth > 0 && info.Savepoint == ""
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
@@ -0,0 +1,200 @@
+package txslog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/sklyar/go-transact"
+	"github.com/sklyar/go-transact/txsql"
+	"github.com/sklyar/go-transact/txtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	someErr := errors.New("some error")
+
+	tests := []struct {
+		name  string
+		opts  []Option
+		setup func(tx *txtest.Tx)
+		fn    transact.TransactionFunc
+		want  []map[string]any
+	}{
+		{
+			name: "commit",
+			setup: func(tx *txtest.Tx) {
+				tx.On("Commit", mock.Anything).Return(nil)
+			},
+			fn: func(_ context.Context) error { return nil },
+			want: []map[string]any{
+				{"level": "DEBUG", "msg": "transaction started", "tx_id": "1", "tx_depth": 0.0, "tx_isolation": "Serializable", "tx_read_only": true},
+				{"level": "DEBUG", "msg": "transaction committed", "tx_id": "1", "tx_depth": 0.0, "tx_isolation": "Serializable", "tx_read_only": true},
+			},
+		},
+		{
+			name: "rollback on error",
+			setup: func(tx *txtest.Tx) {
+				tx.On("Rollback", mock.Anything).Return(nil)
+			},
+			fn: func(_ context.Context) error { return someErr },
+			want: []map[string]any{
+				{"level": "DEBUG", "msg": "transaction started"},
+				{"level": "ERROR", "msg": "transaction rolled back", "cause": "failed to execute transaction function: some error"},
+			},
+		},
+		{
+			name: "commit failure",
+			opts: []Option{WithLevel(slog.LevelInfo), WithErrorLevel(slog.LevelWarn), WithKeys(Keys{ID: "id", Error: "err"})},
+			setup: func(tx *txtest.Tx) {
+				tx.On("Commit", mock.Anything).Return(someErr)
+			},
+			fn: func(_ context.Context) error { return nil },
+			want: []map[string]any{
+				{"level": "INFO", "msg": "transaction started", "id": "1", "tx_depth": 0.0},
+				{"level": "WARN", "msg": "transaction commit failed", "id": "1", "err": "some error"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+			db := txtest.NewDB(t)
+			tx := txtest.NewTx(t)
+			db.On("Begin", mock.Anything, mock.Anything).Return(tx, nil)
+			tt.setup(tx)
+
+			manager, _, err := transact.NewManager(
+				func(_ transact.TransactionStore) (txsql.DB, error) { return db, nil },
+				transact.WithInterceptors(New(logger, tt.opts...)),
+			)
+			require.NoError(t, err)
+
+			_ = manager.BeginFunc(context.Background(), tt.fn,
+				txsql.WithIsolationLevel(txsql.LevelSerializable), txsql.WithReadOnly())
+
+			dec := json.NewDecoder(&buf)
+			for _, want := range tt.want {
+				var got map[string]any
+				require.NoError(t, dec.Decode(&got))
+				assert.Contains(t, got, "duration")
+				for k, v := range want {
+					assert.Equal(t, v, got[k], k)
+				}
+			}
+			assert.False(t, dec.More(), "unexpected records")
+		})
+	}
+}
+
+func TestNewScopes(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(db *txtest.DB, tx *txtest.Tx)
+		run   func(ctx context.Context, manager *transact.Manager) error
+		want  []map[string]any
+	}{
+		{
+			name: "joined scope",
+			setup: func(db *txtest.DB, tx *txtest.Tx) {
+				db.On("Begin", mock.Anything, mock.Anything).Return(tx, nil)
+				tx.On("Commit", mock.Anything).Return(nil)
+			},
+			run: func(ctx context.Context, manager *transact.Manager) error {
+				return manager.BeginFunc(ctx, func(ctx context.Context) error {
+					return manager.BeginFunc(ctx, func(_ context.Context) error { return nil })
+				})
+			},
+			want: []map[string]any{
+				{"level": "DEBUG", "msg": "transaction started", "tx_depth": 0.0},
+				{"level": "DEBUG", "msg": "transaction started", "tx_depth": 1.0},
+				{"level": "DEBUG", "msg": "transaction scope completed", "tx_depth": 1.0},
+				{"level": "DEBUG", "msg": "transaction committed", "tx_depth": 0.0},
+			},
+		},
+		{
+			name: "savepoint scope",
+			setup: func(db *txtest.DB, tx *txtest.Tx) {
+				db.On("Begin", mock.Anything, mock.Anything).Return(tx, nil)
+				tx.On("Savepoint", mock.Anything, "sp_1").Return(nil)
+				tx.On("ReleaseSavepoint", mock.Anything, "sp_1").Return(nil)
+				tx.On("Commit", mock.Anything).Return(nil)
+			},
+			run: func(ctx context.Context, manager *transact.Manager) error {
+				return manager.BeginFunc(ctx, func(ctx context.Context) error {
+					return manager.BeginFunc(ctx, func(_ context.Context) error { return nil }, txsql.WithNested())
+				})
+			},
+			want: []map[string]any{
+				{"level": "DEBUG", "msg": "transaction started", "tx_depth": 0.0},
+				{"level": "DEBUG", "msg": "transaction started", "tx_depth": 1.0},
+				{"level": "DEBUG", "msg": "transaction committed", "tx_depth": 1.0},
+				{"level": "DEBUG", "msg": "transaction committed", "tx_depth": 0.0},
+			},
+		},
+		{
+			name: "transaction required",
+			run: func(ctx context.Context, manager *transact.Manager) error {
+				return manager.BeginFunc(ctx, func(_ context.Context) error { return nil },
+					txsql.WithPropagation(txsql.PropagationMandatory))
+			},
+			want: []map[string]any{
+				{"level": "ERROR", "msg": "transaction begin failed", "tx_id": "", "error": transact.ErrTransactionRequired.Error()},
+			},
+		},
+		{
+			name: "incompatible nested transaction",
+			setup: func(db *txtest.DB, tx *txtest.Tx) {
+				db.On("Begin", mock.Anything, mock.Anything).Return(tx, nil)
+				tx.On("Rollback", mock.Anything).Return(nil)
+			},
+			run: func(ctx context.Context, manager *transact.Manager) error {
+				return manager.BeginFunc(ctx, func(ctx context.Context) error {
+					return manager.BeginFunc(ctx, func(_ context.Context) error { return nil }, txsql.WithReadWrite())
+				}, txsql.WithReadOnly())
+			},
+			want: []map[string]any{
+				{"level": "DEBUG", "msg": "transaction started", "tx_depth": 0.0},
+				{"level": "ERROR", "msg": "transaction begin failed", "tx_id": "1", "tx_depth": 1.0},
+				{"level": "ERROR", "msg": "transaction rolled back", "tx_depth": 0.0},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+			db := txtest.NewDB(t)
+			tx := txtest.NewTx(t)
+			if tt.setup != nil {
+				tt.setup(db, tx)
+			}
+
+			manager, _, err := transact.NewManager(
+				func(_ transact.TransactionStore) (txsql.DB, error) { return db, nil },
+				transact.WithInterceptors(New(logger)),
+			)
+			require.NoError(t, err)
+
+			_ = tt.run(context.Background(), manager)
+
+			dec := json.NewDecoder(&buf)
+			for _, want := range tt.want {
+				var got map[string]any
+				require.NoError(t, dec.Decode(&got))
+				for k, v := range want {
+					assert.Equal(t, v, got[k], k)
+				}
+			}
+			assert.False(t, dec.More(), "unexpected records")
+		})
+	}
+}
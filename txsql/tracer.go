@@ -0,0 +1,47 @@
+package txsql
+
+import "context"
+
+// Tracer traces transactions and the statements executed within them.
+//
+// The context returned by a Start method is passed to the matching End method,
+// so a tracer can keep its span in the context. The context returned by
+// StartTransaction becomes the context of the transaction, so the spans of
+// the statements executed within the transaction are its children.
+type Tracer interface {
+	// StartTransaction is called when a transaction begins.
+	StartTransaction(ctx context.Context, info TransactionInfo) context.Context
+
+	// EndTransaction is called once the transaction has completed.
+	// The error is the reason the transaction has failed or has been rolled back, if any.
+	EndTransaction(ctx context.Context, committed bool, err error)
+
+	// StartQuery is called before a statement is executed.
+	StartQuery(ctx context.Context, info QueryInfo) context.Context
+
+	// EndQuery is called after the statement has been executed.
+	EndQuery(ctx context.Context, err error)
+}
+
+// TransactionInfo describes a traced transaction.
+type TransactionInfo struct {
+	// ID is the ID of the transaction.
+	ID string
+
+	// Options are the options the transaction has been started with.
+	// They are nil if no options have been provided.
+	Options *TxOptions
+}
+
+// QueryInfo describes a traced statement.
+type QueryInfo struct {
+	// Query is the statement.
+	Query string
+
+	// Args are the arguments of the statement.
+	Args []any
+
+	// TxID is the ID of the transaction the statement is executed within.
+	// It is empty if the statement is executed outside a transaction.
+	TxID string
+}
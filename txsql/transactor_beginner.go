@@ -1,6 +1,10 @@
 package txsql
 
-import "context"
+import (
+	"context"
+	"strconv"
+	"time"
+)
 
 // IsolationLevel is the transaction isolation level used in TxOptions.
 type IsolationLevel int
@@ -20,6 +24,30 @@ const (
 	LevelLinearizable
 )
 
+// String returns the name of the transaction isolation level.
+func (i IsolationLevel) String() string {
+	switch i {
+	case LevelDefault:
+		return "Default"
+	case LevelReadUncommitted:
+		return "Read Uncommitted"
+	case LevelReadCommitted:
+		return "Read Committed"
+	case LevelWriteCommitted:
+		return "Write Committed"
+	case LevelRepeatableRead:
+		return "Repeatable Read"
+	case LevelSnapshot:
+		return "Snapshot"
+	case LevelSerializable:
+		return "Serializable"
+	case LevelLinearizable:
+		return "Linearizable"
+	default:
+		return "IsolationLevel(" + strconv.Itoa(int(i)) + ")"
+	}
+}
+
 type TxOptions struct {
 	// Isolation is the transaction isolation level.
 	// If zero, the driver-specific default isolation level is used.
@@ -27,8 +55,98 @@ type TxOptions struct {
 
 	// ReadOnly is whether to set the transaction to read-only.
 	ReadOnly bool
+
+	// ReadWrite is whether the transaction is explicitly requested to be writable.
+	// Transactions are writable unless ReadOnly is set, so it only matters for a transaction
+	// joining an existing one, which must not be read-only then.
+	ReadWrite bool
+
+	// Propagation defines how the transaction relates to a transaction
+	// that already exists in the context.
+	// If zero, PropagationRequired is used.
+	Propagation Propagation
+
+	// Retry is the policy for retrying the transaction on retryable errors.
+	// If nil, the transaction is not retried.
+	Retry *RetryPolicy
+
+	// PanicAsError is whether a panic of the transaction function
+	// is returned as an error instead of being propagated.
+	PanicAsError bool
+
+	// Timeout is the maximum duration of the transaction.
+	// If zero, the transaction has no timeout.
+	Timeout time.Duration
+
+	// Name is the name of the logical transaction, used for diagnostics.
+	Name string
 }
 
+// RetryPolicy configures how a failed transaction is retried.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first one.
+	// If zero, 3 attempts are made.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry.
+	// If zero, 10 milliseconds is used.
+	InitialBackoff time.Duration
+
+	// MaxBackoff is the upper limit of the delay between retries.
+	// If zero, the delay is not limited.
+	MaxBackoff time.Duration
+
+	// Multiplier is the factor the delay grows by after each retry.
+	// If less than 1, 2 is used.
+	Multiplier float64
+
+	// Jitter is the fraction of the delay, from 0 to 1,
+	// by which each delay is randomly shortened.
+	Jitter float64
+
+	// Retryable reports whether the transaction failed with the error can be retried.
+	// If nil, serialization failures, deadlocks and broken connections
+	// that occurred before the commit are retried.
+	Retryable func(err error) bool
+}
+
+// Propagation defines how a transaction behaves when it is started
+// within the context of an existing transaction.
+type Propagation int
+
+// Various propagation modes supported by the transaction manager.
+const (
+	// PropagationRequired joins the existing transaction
+	// or starts a new one if there is none.
+	PropagationRequired Propagation = iota
+
+	// PropagationRequiresNew always starts a new, independent transaction.
+	// The existing transaction, if any, is suspended until the new one completes.
+	// The new transaction uses its own connection, so the connection pool
+	// must allow more than one open connection.
+	PropagationRequiresNew
+
+	// PropagationNested runs within a savepoint of the existing transaction
+	// or starts a new one if there is none.
+	PropagationNested
+
+	// PropagationSupports joins the existing transaction
+	// or runs non-transactionally if there is none.
+	PropagationSupports
+
+	// PropagationMandatory joins the existing transaction
+	// and fails if there is none.
+	PropagationMandatory
+
+	// PropagationNotSupported always runs non-transactionally.
+	// The existing transaction, if any, is suspended.
+	PropagationNotSupported
+
+	// PropagationNever runs non-transactionally
+	// and fails if there is an existing transaction.
+	PropagationNever
+)
+
 // TransactionBeginner provides functionality for starting a new transaction.
 type TransactionBeginner interface {
 	// Begin starts a new transaction and takes context and TxOptions as arguments.
@@ -49,5 +167,64 @@ func WithIsolationLevel(level IsolationLevel) TransactionOption {
 func WithReadOnly() TransactionOption {
 	return func(opts *TxOptions) {
 		opts.ReadOnly = true
+		opts.ReadWrite = false
+	}
+}
+
+// WithReadWrite requests the transaction to be writable.
+// A transaction joining a read-only one fails then instead of silently running read-only.
+func WithReadWrite() TransactionOption {
+	return func(opts *TxOptions) {
+		opts.ReadOnly = false
+		opts.ReadWrite = true
+	}
+}
+
+// WithPropagation sets the transaction propagation mode.
+func WithPropagation(propagation Propagation) TransactionOption {
+	return func(opts *TxOptions) {
+		opts.Propagation = propagation
+	}
+}
+
+// WithNested makes a transaction started within an existing one
+// run inside its own savepoint. If the nested transaction fails, only the
+// changes made after the savepoint are rolled back and the parent
+// transaction can continue.
+// It is a shorthand for WithPropagation(PropagationNested).
+func WithNested() TransactionOption {
+	return WithPropagation(PropagationNested)
+}
+
+// WithRetry makes the transaction retry on retryable errors according to the policy.
+func WithRetry(policy RetryPolicy) TransactionOption {
+	return func(opts *TxOptions) {
+		opts.Retry = &policy
+	}
+}
+
+// WithPanicAsError makes a panic of the transaction function returned as an error
+// instead of being propagated. The transaction is rolled back in both cases.
+func WithPanicAsError() TransactionOption {
+	return func(opts *TxOptions) {
+		opts.PanicAsError = true
+	}
+}
+
+// WithTimeout limits the duration of the transaction.
+// The context of the transaction gets a deadline, and the transaction
+// is rolled back once the deadline passes, even if it is never committed or rolled back.
+// A scope joining an existing transaction keeps the deadline of that transaction.
+func WithTimeout(d time.Duration) TransactionOption {
+	return func(opts *TxOptions) {
+		opts.Timeout = d
+	}
+}
+
+// WithName sets the name of the logical transaction, such as "create_order".
+// Adapters may use it for diagnostics, for example to tag the executed statements.
+func WithName(name string) TransactionOption {
+	return func(opts *TxOptions) {
+		opts.Name = name
 	}
 }
@@ -2,6 +2,7 @@ package txsql
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -12,8 +13,69 @@ func TestWithIsolationLevel(t *testing.T) {
 	assert.Equal(t, LevelDefault, opts.Isolation)
 }
 
+func TestIsolationLevelString(t *testing.T) {
+	tests := []struct {
+		level IsolationLevel
+		want  string
+	}{
+		{LevelDefault, "Default"},
+		{LevelReadCommitted, "Read Committed"},
+		{LevelSerializable, "Serializable"},
+		{IsolationLevel(42), "IsolationLevel(42)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.level.String())
+		})
+	}
+}
+
 func TestWithReadOnly(t *testing.T) {
 	opts := new(TxOptions)
 	WithReadOnly()(opts)
 	assert.True(t, opts.ReadOnly)
 }
+
+func TestWithReadWrite(t *testing.T) {
+	opts := new(TxOptions)
+	WithReadOnly()(opts)
+	WithReadWrite()(opts)
+	assert.False(t, opts.ReadOnly)
+	assert.True(t, opts.ReadWrite)
+}
+
+func TestWithNested(t *testing.T) {
+	opts := new(TxOptions)
+	WithNested()(opts)
+	assert.Equal(t, PropagationNested, opts.Propagation)
+}
+
+func TestWithPropagation(t *testing.T) {
+	opts := new(TxOptions)
+	WithPropagation(PropagationMandatory)(opts)
+	assert.Equal(t, PropagationMandatory, opts.Propagation)
+}
+
+func TestWithRetry(t *testing.T) {
+	opts := new(TxOptions)
+	WithRetry(RetryPolicy{MaxAttempts: 5})(opts)
+	assert.Equal(t, &RetryPolicy{MaxAttempts: 5}, opts.Retry)
+}
+
+func TestWithPanicAsError(t *testing.T) {
+	opts := new(TxOptions)
+	WithPanicAsError()(opts)
+	assert.True(t, opts.PanicAsError)
+}
+
+func TestWithTimeout(t *testing.T) {
+	opts := new(TxOptions)
+	WithTimeout(time.Second)(opts)
+	assert.Equal(t, time.Second, opts.Timeout)
+}
+
+func TestWithName(t *testing.T) {
+	opts := new(TxOptions)
+	WithName("create_order")(opts)
+	assert.Equal(t, "create_order", opts.Name)
+}
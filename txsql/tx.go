@@ -15,4 +15,16 @@ type Tx interface {
 	// Stmt returns a transaction-specific prepared statement
 	// from an existing statement.
 	Stmt(stmt Stmt) Stmt
+
+	// Savepoint establishes a new savepoint with the given name
+	// within the transaction.
+	Savepoint(ctx context.Context, name string) error
+
+	// RollbackToSavepoint rolls back all changes made after the savepoint
+	// with the given name was established. The transaction itself stays active.
+	RollbackToSavepoint(ctx context.Context, name string) error
+
+	// ReleaseSavepoint destroys the savepoint with the given name,
+	// keeping the changes made after it was established.
+	ReleaseSavepoint(ctx context.Context, name string) error
 }
@@ -2,12 +2,18 @@ package txtest
 
 import (
 	"context"
+	"reflect"
 
 	"github.com/sklyar/go-transact/internal/txcontext"
+	"github.com/stretchr/testify/mock"
 )
 
 // WithContext returns a context with an embedded transaction context.
 // The transaction context is created with default values.
+//
+// A context produced by the transaction manager also carries the transaction it belongs to,
+// so it doesn't equal the context returned by WithContext. Use MatchContext to match
+// the contexts passed to mocks by the manager.
 func WithContext(ctx context.Context) context.Context {
 	return WithContextValue(ctx, "1", false)
 }
@@ -20,10 +26,43 @@ func WithChildContext(ctx context.Context) context.Context {
 
 // WithContextValue returns a context with an embedded transaction context.
 // The transaction context is created with the provided id and child flag.
+// A child context has the nesting depth of 1.
 func WithContextValue(ctx context.Context, id string, child bool) context.Context {
+	v := txcontext.Value{ID: id}
+	if child {
+		v.Depth = 1
+	}
+	return txcontext.Wrap(ctx, v)
+}
+
+// WithSavepointContext returns a context with an embedded transaction context
+// of a nested scope that is backed by the savepoint with the provided name.
+func WithSavepointContext(ctx context.Context, savepoint string) context.Context {
 	v := txcontext.Value{
-		ID:    id,
-		Child: child,
+		ID:        "1",
+		Depth:     1,
+		Savepoint: savepoint,
 	}
 	return txcontext.Wrap(ctx, v)
 }
+
+// MatchContext returns an argument matcher for a context carrying the same transaction context as want.
+// The transaction the context belongs to, which is set by the transaction manager, is not compared,
+// so the contexts built by the functions of this package match the ones produced by the manager.
+func MatchContext(want context.Context) any {
+	wantValue, wantOK := txcontext.From(want)
+	return mock.MatchedBy(func(ctx context.Context) bool {
+		v, ok := txcontext.From(ctx)
+		return ok == wantOK && reflect.DeepEqual(withoutOwner(v), withoutOwner(wantValue))
+	})
+}
+
+// withoutOwner returns the transaction context with the owners of the scopes removed.
+func withoutOwner(v txcontext.Value) txcontext.Value {
+	v.Owner = nil
+	if v.Parent != nil {
+		parent := withoutOwner(*v.Parent)
+		v.Parent = &parent
+	}
+	return v
+}
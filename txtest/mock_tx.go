@@ -131,6 +131,20 @@ func (_m *Tx) QueryRow(ctx context.Context, query string, args ...interface{}) t
 	return r0
 }
 
+// ReleaseSavepoint provides a mock function with given fields: ctx, name
+func (_m *Tx) ReleaseSavepoint(ctx context.Context, name string) error {
+	ret := _m.Called(ctx, name)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, name)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Rollback provides a mock function with given fields: ctx
 func (_m *Tx) Rollback(ctx context.Context) error {
 	ret := _m.Called(ctx)
@@ -145,6 +159,34 @@ func (_m *Tx) Rollback(ctx context.Context) error {
 	return r0
 }
 
+// RollbackToSavepoint provides a mock function with given fields: ctx, name
+func (_m *Tx) RollbackToSavepoint(ctx context.Context, name string) error {
+	ret := _m.Called(ctx, name)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, name)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Savepoint provides a mock function with given fields: ctx, name
+func (_m *Tx) Savepoint(ctx context.Context, name string) error {
+	ret := _m.Called(ctx, name)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, name)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Stmt provides a mock function with given fields: stmt
 func (_m *Tx) Stmt(stmt txsql.Stmt) txsql.Stmt {
 	ret := _m.Called(stmt)
@@ -0,0 +1,105 @@
+package txtest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sklyar/go-transact/txsql"
+)
+
+// Names of the spans recorded by Tracer.
+const (
+	SpanTransaction = "transaction"
+	SpanQuery       = "query"
+)
+
+// Span is a span recorded by Tracer.
+type Span struct {
+	// Name is either SpanTransaction or SpanQuery.
+	Name string
+
+	// TxID is the ID of the transaction.
+	TxID string
+	// Options are the options of the transaction.
+	Options *txsql.TxOptions
+
+	// Query is the statement.
+	Query string
+	// Args are the arguments of the statement.
+	Args []any
+
+	// Parent is the span that was current when the span started.
+	Parent *Span
+
+	// Ended is true if the span has ended.
+	Ended bool
+	// Committed is true if the transaction has been committed.
+	Committed bool
+	// Err is the error the span has ended with.
+	Err error
+}
+
+type spanKey struct{}
+
+// Tracer is a txsql.Tracer that records the spans in memory.
+type Tracer struct {
+	mu    sync.Mutex
+	spans []*Span
+}
+
+// NewTracer creates a new recording tracer.
+func NewTracer() *Tracer {
+	return &Tracer{}
+}
+
+// Spans returns the recorded spans in the order they have started.
+func (t *Tracer) Spans() []*Span {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return append([]*Span(nil), t.spans...)
+}
+
+// StartTransaction implements txsql.Tracer.
+func (t *Tracer) StartTransaction(ctx context.Context, info txsql.TransactionInfo) context.Context {
+	return t.start(ctx, &Span{Name: SpanTransaction, TxID: info.ID, Options: info.Options})
+}
+
+// EndTransaction implements txsql.Tracer.
+func (t *Tracer) EndTransaction(ctx context.Context, committed bool, err error) {
+	t.end(ctx, committed, err)
+}
+
+// StartQuery implements txsql.Tracer.
+func (t *Tracer) StartQuery(ctx context.Context, info txsql.QueryInfo) context.Context {
+	return t.start(ctx, &Span{Name: SpanQuery, TxID: info.TxID, Query: info.Query, Args: info.Args})
+}
+
+// EndQuery implements txsql.Tracer.
+func (t *Tracer) EndQuery(ctx context.Context, err error) {
+	t.end(ctx, false, err)
+}
+
+func (t *Tracer) start(ctx context.Context, span *Span) context.Context {
+	span.Parent, _ = ctx.Value(spanKey{}).(*Span)
+
+	t.mu.Lock()
+	t.spans = append(t.spans, span)
+	t.mu.Unlock()
+
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+func (t *Tracer) end(ctx context.Context, committed bool, err error) {
+	span, ok := ctx.Value(spanKey{}).(*Span)
+	if !ok {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	span.Ended = true
+	span.Committed = committed
+	span.Err = err
+}
@@ -0,0 +1,194 @@
+package transact
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sklyar/go-transact/internal/stack"
+	"github.com/sklyar/go-transact/internal/txcontext"
+)
+
+// LongRunningTransaction describes a transaction found by the watchdog.
+type LongRunningTransaction struct {
+	// ID is the ID of the transaction.
+	ID string
+
+	// Age is the time since the transaction has begun.
+	Age time.Duration
+
+	// Stack is the stack trace of the call that has begun the transaction.
+	Stack string
+
+	// LastStatement is the last statement executed within the transaction.
+	// It is empty if no statements have been executed.
+	LastStatement string
+
+	// RolledBack is true if the watchdog has rolled the transaction back.
+	RolledBack bool
+}
+
+// WatchdogOptions configures the watchdog of a manager.
+type WatchdogOptions struct {
+	// Threshold is the age after which a transaction is considered long-running.
+	Threshold time.Duration
+
+	// Interval is the interval between scans of the open transactions.
+	// If zero, half of the threshold is used.
+	Interval time.Duration
+
+	// OnLongRunning is called once for every transaction that has been open longer than the threshold.
+	// It is called from the watchdog goroutine, so it must not block for long.
+	OnLongRunning func(tx LongRunningTransaction)
+
+	// RollBack makes the watchdog roll long-running transactions back.
+	// Committing such a transaction afterwards returns ErrLongRunningTransaction.
+	RollBack bool
+}
+
+// WithWatchdog starts a watchdog that periodically looks for transactions
+// open longer than the threshold. The watchdog runs until the manager is closed.
+// NewManager fails if the threshold is not positive.
+//
+// The watchdog captures the stack of every Begin call and keeps track of the last
+// statement of every transaction, which adds a small overhead to both.
+func WithWatchdog(opts WatchdogOptions) Option {
+	return func(m *Manager) {
+		if opts.Interval <= 0 {
+			opts.Interval = opts.Threshold / 2
+		}
+		if opts.Interval <= 0 {
+			// the threshold is too short to be halved.
+			opts.Interval = opts.Threshold
+		}
+
+		m.watchdog = &watchdog{
+			opts: opts,
+			stop: make(chan struct{}),
+			done: make(chan struct{}),
+		}
+	}
+}
+
+// validate checks that the options can be used by the watchdog.
+func (o WatchdogOptions) validate() error {
+	if o.Threshold <= 0 {
+		return fmt.Errorf("invalid watchdog threshold %s: must be positive", o.Threshold)
+	}
+	return nil
+}
+
+// watchdog looks for long-running transactions.
+type watchdog struct {
+	opts WatchdogOptions
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// run scans the open transactions of the store until the watchdog is stopped.
+func (w *watchdog) run(s *store) {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.scan(s)
+		}
+	}
+}
+
+// scan reports the long-running transactions of the store that have not been reported yet.
+func (w *watchdog) scan(s *store) {
+	for _, tx := range s.transactions() {
+		age := time.Since(tx.startedAt)
+		if age < w.opts.Threshold || tx.reported.Swap(true) {
+			continue
+		}
+
+		info := LongRunningTransaction{
+			ID:    tx.id,
+			Age:   age,
+			Stack: formatStack(tx.beginStack),
+		}
+		if query := tx.lastStatement.Load(); query != nil {
+			info.LastStatement = *query
+		}
+		if w.opts.RollBack {
+			info.RolledBack = tx.kill(context.Background(), ErrLongRunningTransaction)
+		}
+
+		if w.opts.OnLongRunning != nil {
+			w.opts.OnLongRunning(info)
+		}
+	}
+}
+
+// close stops the watchdog and waits for it to finish.
+func (w *watchdog) close() {
+	w.stopOnce.Do(func() { close(w.stop) })
+	<-w.done
+}
+
+// kill rolls back the transaction behind the back of its owner, unless it has been completed.
+// The interceptors and the hooks are called with ctx, which must not be canceled.
+// The owner gets the cause on the next commit. It reports whether the transaction has been rolled back.
+//
+// The transaction stays in the store until its owner commits or rolls it back,
+// so the statements the owner executes in the meantime fail on the rolled back
+// transaction instead of running outside of it.
+func (tx *Transaction) kill(ctx context.Context, cause error) bool {
+	tx.mu.Lock()
+	alive := !tx.commit && !tx.rollback
+	tx.mu.Unlock()
+	if !alive {
+		return false
+	}
+
+	killed := false
+	ctx = txcontext.Wrap(ctx, txcontext.Value{ID: tx.id})
+	_, err := tx.intercept(ctx, OpRollback, cause, func(ctx context.Context) (context.Context, error) {
+		tx.mu.Lock()
+		killed = !tx.commit && !tx.rollback
+		if killed {
+			tx.rollback, tx.killed = true, true
+			tx.abortCause = cause
+			if tx.rollbackCause == nil {
+				tx.rollbackCause = cause
+			}
+		}
+		tx.mu.Unlock()
+		if !killed {
+			// the owner has completed the transaction in the meantime.
+			return ctx, nil
+		}
+
+		err := tx.rollbackTx(ctx)
+		if cerr := tx.complete(ctx, OutcomeRolledBack, nil); cerr != nil {
+			err = errors.Join(err, cerr)
+		}
+		return ctx, err
+	})
+	return killed && err == nil
+}
+
+// formatStack formats the stack skipping the frames of the manager itself.
+func formatStack(pcs []uintptr) string {
+	const pkg = "github.com/sklyar/go-transact."
+
+	return stack.Format(pcs, func(frame runtime.Frame) bool {
+		return strings.HasPrefix(frame.Function, pkg+"(*Manager)") ||
+			strings.HasPrefix(frame.Function, pkg+"intercept") ||
+			strings.HasPrefix(frame.Function, pkg+"Do[")
+	})
+}
@@ -0,0 +1,180 @@
+package transact
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sklyar/go-transact/txsql"
+	"github.com/sklyar/go-transact/txtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithWatchdog(t *testing.T) {
+	tests := []struct {
+		name     string
+		rollBack bool
+	}{
+		{name: "report only"},
+		{name: "roll back", rollBack: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := txtest.NewDB(t)
+			tx := txtest.NewTx(t)
+			db.On("Begin", mock.Anything, nilTxOptions).Return(tx, nil)
+			tx.On("Exec", mock.Anything, "SELECT pg_sleep(3600)").Return(nil, nil)
+
+			var reported []LongRunningTransaction
+			manager, _, err := NewManager(func(_ TransactionStore) (txsql.DB, error) { return db, nil },
+				WithWatchdog(WatchdogOptions{
+					Threshold:     time.Millisecond,
+					Interval:      time.Hour,
+					OnLongRunning: func(tx LongRunningTransaction) { reported = append(reported, tx) },
+					RollBack:      tt.rollBack,
+				}))
+			require.NoError(t, err)
+			defer manager.Close()
+
+			ctx, transaction, err := manager.Begin(context.Background())
+			require.NoError(t, err)
+			_, err = transaction.Exec(ctx, "SELECT pg_sleep(3600)")
+			require.NoError(t, err)
+
+			if tt.rollBack {
+				tx.On("Rollback", mock.Anything).Return(nil).Once()
+			}
+
+			time.Sleep(2 * time.Millisecond)
+			manager.watchdog.scan(manager.store)
+			// a transaction is reported only once.
+			manager.watchdog.scan(manager.store)
+
+			require.Len(t, reported, 1)
+			assert.Equal(t, "1", reported[0].ID)
+			assert.GreaterOrEqual(t, reported[0].Age, time.Millisecond)
+			assert.Equal(t, "SELECT pg_sleep(3600)", reported[0].LastStatement)
+			assert.Contains(t, reported[0].Stack, "TestWithWatchdog")
+			assert.NotContains(t, reported[0].Stack, "(*Manager)")
+			assert.Equal(t, tt.rollBack, reported[0].RolledBack)
+
+			if !tt.rollBack {
+				tx.On("Commit", mock.Anything).Return(nil)
+				_, err = transaction.Commit(ctx)
+				assert.NoError(t, err)
+				return
+			}
+
+			// the statements of the owner keep going to the rolled back transaction
+			// rather than running outside of it.
+			stored, ok := manager.store.Transaction(ctx)
+			require.True(t, ok)
+			assert.Same(t, transaction, stored)
+
+			_, err = transaction.Commit(ctx)
+			assert.ErrorIs(t, err, ErrLongRunningTransaction)
+			assert.Equal(t, 0, manager.store.Len())
+		})
+	}
+}
+
+func TestManagerCloseStopsWatchdog(t *testing.T) {
+	manager, _, err := NewManager(func(_ TransactionStore) (txsql.DB, error) { return txtest.NewDB(t), nil },
+		WithWatchdog(WatchdogOptions{Threshold: time.Millisecond}))
+	require.NoError(t, err)
+
+	assert.NoError(t, manager.Close())
+	assert.NoError(t, manager.Close())
+
+	select {
+	case <-manager.watchdog.done:
+	default:
+		t.Fatal("watchdog is still running")
+	}
+}
+
+func TestWithWatchdogOptions(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         WatchdogOptions
+		wantInterval time.Duration
+		wantErr      string
+	}{
+		{name: "default interval", opts: WatchdogOptions{Threshold: time.Second}, wantInterval: 500 * time.Millisecond},
+		{name: "too short threshold to halve", opts: WatchdogOptions{Threshold: 1}, wantInterval: 1},
+		{name: "zero threshold", opts: WatchdogOptions{}, wantErr: "invalid watchdog threshold 0s: must be positive"},
+		{
+			name:    "negative threshold",
+			opts:    WatchdogOptions{Threshold: -time.Second, Interval: time.Second},
+			wantErr: "invalid watchdog threshold -1s: must be positive",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager, _, err := NewManager(func(_ TransactionStore) (txsql.DB, error) { return txtest.NewDB(t), nil },
+				WithWatchdog(tt.opts))
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				assert.Nil(t, manager)
+				return
+			}
+			require.NoError(t, err)
+			defer manager.Close()
+
+			assert.Equal(t, tt.wantInterval, manager.watchdog.opts.Interval)
+		})
+	}
+}
+
+// activeMetrics counts the transactions that have begun and not completed yet.
+type activeMetrics struct {
+	active    atomic.Int64
+	unmatched atomic.Int64
+}
+
+func (m *activeMetrics) TransactionBegun() {
+	m.active.Add(1)
+}
+
+func (m *activeMetrics) TransactionCompleted(Outcome, time.Duration, int) {
+	if m.active.Add(-1) < 0 {
+		m.unmatched.Add(1)
+	}
+}
+
+func TestWatchdogRollsBackBegunTransactions(t *testing.T) {
+	db := txtest.NewDB(t)
+	tx := txtest.NewTx(t)
+	db.On("Begin", mock.Anything, nilTxOptions).Return(tx, nil)
+	tx.On("Commit", mock.Anything).Return(nil).Maybe()
+	tx.On("Rollback", mock.Anything).Return(nil).Maybe()
+
+	metrics := new(activeMetrics)
+	manager, _, err := NewManager(func(_ TransactionStore) (txsql.DB, error) { return db, nil },
+		WithWatchdog(WatchdogOptions{Threshold: time.Nanosecond, Interval: 50 * time.Microsecond, RollBack: true}),
+		WithInterceptors(func(ctx context.Context, _ InterceptorInfo, next Handler) error {
+			return next(ctx)
+		}),
+		WithMetrics(metrics))
+	require.NoError(t, err)
+	defer manager.Close()
+
+	// the watchdog may roll a transaction back as soon as it is added to the store,
+	// so the transaction must be set up completely by then.
+	for i := 0; i < 20; i++ {
+		err := manager.BeginFunc(context.Background(), func(_ context.Context) error {
+			time.Sleep(time.Millisecond)
+			return nil
+		})
+		if err != nil {
+			assert.ErrorIs(t, err, ErrLongRunningTransaction)
+		}
+	}
+
+	assert.Zero(t, metrics.unmatched.Load())
+	assert.Eventually(t, func() bool { return metrics.active.Load() == 0 }, time.Second, time.Millisecond)
+}